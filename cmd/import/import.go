@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/importer"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+const credentialsFile = "credentials.json"
+
+var GitCommit string
+
+type Options struct {
+	File     string
+	Account  string
+	Password string
+	DryRun   bool
+}
+
+func getOptions() Options {
+	defer flag.Parse()
+
+	var options Options
+
+	flag.StringVar(&options.File, "file", "", "Bancolombia movements file, .csv or .xlsx, or a .pdf statement")
+	flag.StringVar(&options.Account, "account", "", "Account number of the movements, e.g. 1234, PDF statements tell it")
+	flag.StringVar(&options.Password, "password", "", "Password of encrypted PDF statements, pdf-statements.password by default")
+	flag.BoolVar(&options.DryRun, "dryRun", false, "Tell what will happen but not execute")
+
+	return options
+}
+
+func getAuth() (types.Auth, error) {
+	credFile, err := os.Open(credentialsFile)
+	if err != nil {
+		return types.Auth{}, err
+	}
+	defer credFile.Close()
+
+	authBytes, err := io.ReadAll(credFile)
+	if err != nil {
+		return types.Auth{}, err
+	}
+
+	var auth types.Auth
+	err = json.Unmarshal(authBytes, &auth)
+	if err != nil {
+		return types.Auth{}, err
+	}
+
+	return auth, nil
+}
+
+func printTransactions(status string, txs []*types.TransactionInfo) {
+	const dateFormat = "2006-01-02"
+	for _, t := range txs {
+		fmt.Printf("%s | %s | %s %s | %s\n", status, t.Date.Format(dateFormat), t.Direction, t.Value, t.Place)
+	}
+}
+
+func readMovements(options Options) ([]*types.TransactionInfo, error) {
+	rows, err := importer.ReadFile(options.File)
+	if err != nil {
+		return nil, err
+	}
+
+	return importer.ParseBancolombiaMovements(rows, options.Account)
+}
+
+func readPDFStatement(options Options, auth types.Auth) ([]*types.TransactionInfo, error) {
+	password := options.Password
+	if password == "" {
+		password = auth.PDFStatements.Password
+	}
+
+	lines, err := importer.ReadPDFFile(options.File, password)
+	if err != nil {
+		return nil, err
+	}
+
+	account := options.Account
+	if account == "" {
+		var ok bool
+		if account, ok = importer.StatementAccount(lines); !ok {
+			return nil, fmt.Errorf("account number not found in statement, it must be given")
+		}
+	}
+
+	return importer.ParsePDFStatement(lines, account, importer.StatementSignConvention(auth.PDFStatements, account))
+}
+
+func main() {
+	common.PrintVersion(GitCommit)
+	options := getOptions()
+
+	isPDF := strings.EqualFold(filepath.Ext(options.File), ".pdf")
+	if options.File == "" || (options.Account == "" && !isPDF) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	auth, err := getAuth()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var transactions []*types.TransactionInfo
+	if isPDF {
+		transactions, err = readPDFStatement(options, auth)
+	} else {
+		transactions, err = readMovements(options)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	status, err := sync.Import(auth, transactions, options.DryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	created := "CREATED"
+	if options.DryRun {
+		created = "PENDING"
+	}
+	printTransactions(created, status.SuccessfulTxs)
+	printTransactions("FAILED", status.FailedTxs)
+	printTransactions("BOOKED", status.BookedTxs)
+	for _, e := range status.UnknownAccounts {
+		fmt.Printf("UNKNOWN | %s\n", e)
+	}
+
+	if len(status.FailedTxs) > 0 {
+		os.Exit(1)
+	}
+}
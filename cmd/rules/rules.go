@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank"
+	"github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap"
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/merchant"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+const credentialsFile = "credentials.json"
+
+var GitCommit string
+
+type Options struct {
+	Rules   string
+	Mailbox string
+	Days    int
+}
+
+func getOptions() Options {
+	defer flag.Parse()
+
+	var options Options
+
+	flag.StringVar(&options.Rules, "rules", "", "Rules file to test, category-rules-file by default")
+	flag.StringVar(&options.Mailbox, "mailbox", sync.ArchivedMailbox, "Mailbox with the past bank messages")
+	flag.IntVar(&options.Days, "days", 30, "Days of past messages to categorize")
+
+	return options
+}
+
+func getAuth() (types.Auth, error) {
+	credFile, err := os.Open(credentialsFile)
+	if err != nil {
+		return types.Auth{}, err
+	}
+	defer credFile.Close()
+
+	authBytes, err := io.ReadAll(credFile)
+	if err != nil {
+		return types.Auth{}, err
+	}
+
+	var auth types.Auth
+	err = json.Unmarshal(authBytes, &auth)
+	if err != nil {
+		return types.Auth{}, err
+	}
+
+	return auth, nil
+}
+
+func main() {
+	const dateFormat = "2006-01-02"
+
+	common.PrintVersion(GitCommit)
+	options := getOptions()
+
+	auth, err := getAuth()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rulesFile := options.Rules
+	if rulesFile == "" {
+		rulesFile = auth.CategoryRulesFile
+	}
+	if rulesFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ruleSet, err := sync.LoadRules(rulesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	merchants, err := merchant.Load(auth.MerchantAliasesFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	banks := bank.GetBanks()
+	if auth.BankDefinitionsDir != "" {
+		definitions, err := bank.LoadDefinitions(auth.BankDefinitionsDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		banks = append(banks[:len(banks):len(banks)], definitions...)
+	}
+
+	mailClient, err := imap.GetMailClient(auth.Addr, auth.Username, auth.Password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer mailClient.Logout()
+
+	since := time.Now().AddDate(0, 0, -options.Days)
+	msgs, err := sync.GetEmailFromMailbox(mailClient, imaptypes.Mailbox(options.Mailbox), banks, since)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	transactions, parseErrors := sync.ExtractTransactionInfoFromMessages(msgs)
+	sync.NormalizeMerchants(merchants, transactions)
+
+	matched := 0
+	for _, t := range transactions {
+		category, tags, name := "PENDING", "", "-"
+		if rule, ok := ruleSet.Match(t); ok {
+			category, tags, name = rule.Category, strings.Join(rule.Tags, ", "), rule.Name
+			matched++
+		}
+
+		place := t.Place
+		if t.Merchant != "" {
+			place = fmt.Sprintf("%s (%s)", t.Place, t.Merchant)
+		}
+
+		fmt.Printf("%s | %s %s | %s %s | %s -> %s [%s] (%s)\n",
+			t.Date.In(common.GetLocalLocation()).Format(dateFormat), t.Bank.Name(), t.Account,
+			t.Type, t.Value, place, category, tags, name)
+	}
+
+	for _, e := range parseErrors {
+		fmt.Printf("UNPARSED | %s\n", e)
+	}
+
+	fmt.Printf("%d of %d transactions matched a rule\n", matched, len(transactions))
+}
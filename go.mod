@@ -10,9 +10,11 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.5.2
 	github.com/emersion/go-imap v1.2.0
 	github.com/emersion/go-message v0.15.0
+	github.com/ledongthuc/pdf v0.0.0-20220302134840-0c2507a12d80
 	github.com/twilio/twilio-go v0.18.0
 	go.uber.org/zap v1.19.1
 	golang.org/x/text v0.3.7
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -1,7 +1,6 @@
 package bancolombia
 
 import (
-	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -15,140 +14,218 @@ import (
 type Bancolombia struct {
 }
 
-func (b Bancolombia) FilterMessage(msg imaptypes.Message) bool {
-	keep := true
-	keep = keep && msg.Message != nil
-	keep = keep && msg.Message.Envelope != nil
-	if keep {
-		keep = false
-		for _, address := range msg.Message.Envelope.From {
-			from := address.Address()
-			if from == "alertasynotificaciones@notificacionesbancolombia.com" {
-				keep = true
-				break
-			}
-		}
-	}
+// amountExp matches amounts in pesos like "$12.900,00" and in other currencies like "USD12.99"
+const amountExp = `(?:\$|(?P<currency>[A-Z]{3})\s?)(?P<value>[0-9,\.]+)`
+
+type messageCase struct {
+	keyword   string
+	regexp    *regexp.Regexp
+	direction synctypes.Direction
+	// counterpart is matched against the place to get the account that received the money
+	counterpart *regexp.Regexp
+	refund      bool
+	cash        bool
+	// installments tells if the message can state that the purchase was deferred to several monthly payments
+	installments bool
+}
 
-	if keep {
-		text := string(msg.RawBody)
-		lowerCaseText := strings.ToLower(text)
+// installmentsRegexp matches the installments of credit card purchases, e.g. "a 12 cuotas"
+var installmentsRegexp = regexp.MustCompile(`(?i)\ba (?P<installments>\d{1,2}) cuotas\b`)
+
+var feeRegexp = regexp.MustCompile(`Bancolombia le informa (?P<type>Cobro) (?:de )?por ` + amountExp + ` (?:de|por) (?P<place>.+?) (?:a|en|desde) (?:su )?(?:T\.(?:Cred|Deb)|cta) \*(?P<account>\d{4})`)
+
+var refundRegexp = regexp.MustCompile(`Bancolombia le informa (?P<type>Reverso|Devoluci[oó]n)(?: de compra)? por ` + amountExp + ` de (?P<place>.+?) (?:a|en) (?:su )?(?:T\.(?:Cred|Deb)|cta) \*(?P<account>\d{4})`)
+
+var payrollRegexp = regexp.MustCompile(`Bancolombia le informa (?P<type>Pago de N[oó]mina) de (?P<place>.+?) por ` + amountExp + ` en (?:su )?cta \*(?P<account>\d{4})`)
+
+// cases are checked in order, so the incoming money, refund and fee cases must go before "pago", "compra"
+// and "transferencia" since their messages can also contain those words
+var messageCases = []messageCase{
+	{
+		keyword:   "pago de nomina",
+		regexp:    payrollRegexp,
+		direction: synctypes.Credit,
+	},
+	{
+		keyword:   "pago de nómina",
+		regexp:    payrollRegexp,
+		direction: synctypes.Credit,
+	},
+	{
+		keyword:   "recibió",
+		regexp:    regexp.MustCompile(`Bancolombia le informa que (?P<type>recibió) (?:una transferencia |un pago )?por ` + amountExp + ` de (?P<place>.+?) en (?:su )?cta \*(?P<account>\d{4})`),
+		direction: synctypes.Credit,
+	},
+	{
+		keyword:   "abono",
+		regexp:    regexp.MustCompile(`Bancolombia le informa (?P<type>Abono) por ` + amountExp + ` de (?P<place>.+?) en (?:su )?cta \*(?P<account>\d{4})`),
+		direction: synctypes.Credit,
+	},
+	{
+		keyword:   "consignación",
+		regexp:    regexp.MustCompile(`(?m)Bancolombia le informa (?P<type>Consignación) por ` + amountExp + ` en (?:su )?cta \*(?P<account>\d{4}) (?:desde|en) (?P<place>.+?)\.(?:\s+\d{2}[/:]\d{2}|\s*$)`),
+		direction: synctypes.Credit,
+	},
+	{
+		keyword:   "reverso",
+		regexp:    refundRegexp,
+		direction: synctypes.Credit,
+		refund:    true,
+	},
+	{
+		keyword:   "devolución",
+		regexp:    refundRegexp,
+		direction: synctypes.Credit,
+		refund:    true,
+	},
+	{
+		keyword:   "devolucion",
+		regexp:    refundRegexp,
+		direction: synctypes.Credit,
+		refund:    true,
+	},
+	{
+		keyword: "cuota de manejo",
+		regexp:  feeRegexp,
+	},
+	{
+		keyword: "cobro",
+		regexp:  feeRegexp,
+	},
+	{
+		keyword: "retiro",
+		regexp:  regexp.MustCompile(`Bancolombia le informa (?P<type>Retiro) por ` + amountExp + ` en (?P<place>.+)\..+(?:T\.Deb|cta) \*(?P<account>\d{4})\.`),
+		cash:    true,
+	},
+	{
+		keyword:     "pago",
+		regexp:      regexp.MustCompile(`Bancolombia le informa (?P<type>\w+) por ` + amountExp + ` a (?P<place>.+) desde (?:cta|T\.CRED) \*(?P<account>\d{4})\.`),
+		counterpart: regexp.MustCompile(`^T\.CRED \*(?P<counterpart>\d{4})$`),
+	},
+	{
+		keyword:      "compra",
+		regexp:       regexp.MustCompile(`Bancolombia le informa (?P<type>\w+) por ` + amountExp + ` en (?P<place>.+)\..+T\.(?:Cred|Deb) \*(?P<account>\d{4})[\. ]`),
+		installments: true,
+	},
+	{
+		keyword:     "transferencia",
+		regexp:      regexp.MustCompile(`Bancolombia le informa (?P<type>\w+) por ` + amountExp + ` desde cta \*(?P<account>\d{4}).+cta (?P<place>\d{11,16})\.`),
+		counterpart: regexp.MustCompile(`^(?P<counterpart>\d{11,16})$`),
+	},
+}
 
-		shouldProcess := strings.Contains(lowerCaseText, "pago")
-		shouldProcess = shouldProcess || strings.Contains(lowerCaseText, "compra")
-		shouldProcess = shouldProcess || strings.Contains(lowerCaseText, "transferencia")
+// cardRegexpFormat matches the kind of the card of the account, e.g. "T.Cred *1234"
+const cardRegexpFormat = `(?i)\bT\.(?P<card>Cred|Deb) \*%s\b`
 
-		keep = shouldProcess
-	}
+func cardType(text, account string) synctypes.CardType {
+	cardRegexp := regexp.MustCompile(fmt.Sprintf(cardRegexpFormat, regexp.QuoteMeta(account)))
 
-	return keep
+	switch strings.ToLower(common.ExtractFieldsStringWithRegexp(text, cardRegexp)["card"]) {
+	case "cred":
+		return synctypes.CreditCard
+	case "deb":
+		return synctypes.DebitCard
+	default:
+		return ""
+	}
 }
 
-var regexpMap = map[string]*regexp.Regexp{
-	"pago":          regexp.MustCompile(`Bancolombia le informa (?P<type>\w+) por \$(?P<value>[0-9,\.]+) a (?P<place>.+) desde (?:cta|T\.CRED) \*(?P<account>\d{4})\.`),
-	"compra":        regexp.MustCompile(`Bancolombia le informa (?P<type>\w+) por \$(?P<value>[0-9,\.]+) en (?P<place>.+)\..+T\.(?:Cred|Deb) \*(?P<account>\d{4})\.`),
-	"transferencia": regexp.MustCompile(`Bancolombia le informa (?P<type>\w+) por \$(?P<value>[0-9,\.]+) desde cta \*(?P<account>\d{4}).+cta (?P<place>\d{11,16})\.`),
-}
+// timeSuffixRegexp matches the time that some messages write right after the place
+var timeSuffixRegexp = regexp.MustCompile(`\s+\d{1,2}:\d{2}$`)
 
-func (b Bancolombia) ExtractTransactionInfoFromMessage(msg imaptypes.Message) (*synctypes.TransactionInfo, error) {
-	text := string(msg.RawBody)
+func selectMessageCase(text string) (messageCase, bool) {
 	lowerCaseText := strings.ToLower(text)
-
-	var selected string
-	for key := range regexpMap {
-		if strings.Contains(lowerCaseText, key) {
-			selected = key
-			break
+	for _, c := range messageCases {
+		if strings.Contains(lowerCaseText, c.keyword) {
+			return c, true
 		}
 	}
 
-	if selected == "" {
-		return nil, errors.New("message does not match any transaction type case")
-	}
-
-	selectedRegexp := regexpMap[selected]
+	return messageCase{}, false
+}
 
-	result := common.ExtractFieldsStringWithRegexp(text, selectedRegexp)
+func (b Bancolombia) Name() string {
+	return "Bancolombia"
+}
 
-	if !common.ContainsAllRequiredFields(result) {
-		return nil, fmt.Errorf("message does not contain all required fields - result [%+v]", result)
+func isFromBancolombia(msg imaptypes.Message) bool {
+	if msg.Message == nil || msg.Message.Envelope == nil {
+		return false
 	}
-	value, err := getValueFromText(result["value"])
-	if err != nil {
-		return nil, err
+
+	for _, address := range msg.Message.Envelope.From {
+		if address.Address() == "alertasynotificaciones@notificacionesbancolombia.com" {
+			return true
+		}
 	}
 
-	return &synctypes.TransactionInfo{
-		MsgId:   msg.SeqNum,
-		Type:    result["type"],
-		Place:   result["place"],
-		Value:   value,
-		Account: result["account"],
-		Date:    msg.Envelope.Date,
-	}, nil
+	return false
 }
 
-// This would be way easier if Bancolombia had a consistent use of commas and dots inside the currency
-var currencyRegexp = regexp.MustCompile(`^(?P<integer>[0-9\.,]+)[\.,](?P<decimal>\d{2})$`)
-var currencyRegexpWithoutDecimal = regexp.MustCompile(`^(?P<integer>[0-9\.,]+)`)
-
-func getValueFromTextWithDecimal(s string) (string, error) {
-	if !currencyRegexp.MatchString(s) {
-		return "", fmt.Errorf("string [%s] does not match regex [%s]", s, currencyRegexp.String())
-	}
-
-	res := common.ExtractFieldsStringWithRegexp(s, currencyRegexp)
-	integer, ok := res["integer"]
-	if !ok {
-		return "", fmt.Errorf("string [%s] should have an integer part", s)
-	}
+func (b Bancolombia) FilterMessage(msg imaptypes.Message) bool {
+	keep := isFromBancolombia(msg)
+	keep = keep && !isStatement(msg)
 
-	decimal, ok := res["decimal"]
-	if !ok {
-		return "", fmt.Errorf("string [%s] should have a decimal part", s)
+	if keep {
+		_, keep = selectMessageCase(string(msg.RawBody))
 	}
 
-	integer = strings.ReplaceAll(integer, ",", "")
-	integer = strings.ReplaceAll(integer, ".", "")
-	valueStr := integer + "." + decimal
-
-	return valueStr, nil
+	return keep
 }
 
-func getValueFromTextWithoutDecimal(s string) (string, error) {
-	if !currencyRegexpWithoutDecimal.MatchString(s) {
-		return "", fmt.Errorf("string [%s] does not match regex without decimal [%s]", s, currencyRegexp.String())
-	}
+func (b Bancolombia) ExtractTransactionInfoFromMessage(msg imaptypes.Message) (*synctypes.TransactionInfo, error) {
+	text := string(msg.RawBody)
 
-	res := common.ExtractFieldsStringWithRegexp(s, currencyRegexpWithoutDecimal)
-	integer, ok := res["integer"]
+	selected, ok := selectMessageCase(text)
 	if !ok {
-		return "", fmt.Errorf("string [%s] should have an integer part", s)
+		return nil, synctypes.NewNoTemplateMatchedError(b.Name())
 	}
 
-	integer = strings.ReplaceAll(integer, ",", "")
-	integer = strings.ReplaceAll(integer, ".", "")
-	decimal := "0"
-	valueStr := integer + "." + decimal
+	result := common.ExtractFieldsStringWithRegexp(text, selected.regexp)
 
-	return valueStr, nil
-}
-
-func getValueFromText(s string) (synctypes.Currency, error) {
-	valueStr, err := getValueFromTextWithDecimal(s)
+	if missing := common.MissingRequiredFields(result); len(missing) > 0 {
+		return nil, synctypes.NewMissingFieldError(b.Name(), strings.Join(missing, ","))
+	}
+	value, err := common.GetValueFromText(result["value"], result["currency"])
 	if err != nil {
-		valueStr, err = getValueFromTextWithoutDecimal(s)
+		return nil, synctypes.NewBadAmountError(b.Name(), err)
 	}
 
-	if err != nil {
-		return synctypes.Currency{}, err
+	var counterpart string
+	if selected.counterpart != nil {
+		counterpart = common.ExtractFieldsStringWithRegexp(result["place"], selected.counterpart)["counterpart"]
+	}
+	if selected.cash {
+		counterpart = synctypes.CashCounterpart
 	}
 
-	value, err := strconv.ParseFloat(valueStr, 64)
+	var refund *synctypes.Refund
+	if selected.refund {
+		refund = &synctypes.Refund{}
+	}
 
-	var currency synctypes.Currency
-	currency.Code = "COP"
-	currency.Rate = &value
+	var installments int
+	if selected.installments {
+		if count, ok := common.ExtractFieldsStringWithRegexp(text, installmentsRegexp)["installments"]; ok {
+			installments, _ = strconv.Atoi(count)
+		}
+	}
+
+	date, dateSource := common.GetTransactionDate(text, msg.Envelope.Date)
 
-	return currency, err
+	return &synctypes.TransactionInfo{
+		Bank:         b,
+		MsgId:        msg.SeqNum,
+		Type:         result["type"],
+		Place:        timeSuffixRegexp.ReplaceAllString(result["place"], ""),
+		Value:        value,
+		Account:      result["account"],
+		Card:         cardType(text, result["account"]),
+		Date:         date,
+		DateSource:   dateSource,
+		Direction:    selected.direction,
+		Counterpart:  counterpart,
+		Refund:       refund,
+		Installments: installments,
+	}, nil
 }
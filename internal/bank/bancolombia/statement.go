@@ -0,0 +1,87 @@
+package bancolombia
+
+import (
+	"regexp"
+	"time"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+var statementAccountRegexp = regexp.MustCompile(`(?i)(?:T\.\s?Cred|Tarjeta de Cr[eé]dito)[^*]*\*(?P<account>\d{4})`)
+
+var statementBalanceRegexp = regexp.MustCompile(`(?i)(?:saldo|pago) total(?: a pagar)?:?\s*` + amountExp)
+
+var statementMinimumPaymentRegexp = regexp.MustCompile(`(?i)pago m[ií]nimo:?\s*` + amountExp)
+
+var statementDueDateRegexp = regexp.MustCompile(`(?i)fecha l[ií]mite de pago:?\s*(?P<date>\d{2}/\d{2}/\d{4})`)
+
+var statementSubjectRegexp = regexp.MustCompile(`(?i)^\s*extracto\b`)
+
+var statementPhraseRegexp = regexp.MustCompile(`(?i)\bextracto de (?:su|tu) (?:T\.\s?Cred|Tarjeta de Cr[eé]dito)`)
+
+// isStatement tells statements apart by their subject or by the phrase that announces them, other alerts may
+// mention the extracto too, e.g. in their footer
+func isStatement(msg imaptypes.Message) bool {
+	if msg.Message != nil && msg.Message.Envelope != nil && statementSubjectRegexp.MatchString(msg.Message.Envelope.Subject) {
+		return true
+	}
+
+	return statementPhraseRegexp.MatchString(string(msg.RawBody))
+}
+
+func (b Bancolombia) FilterStatementMessage(msg imaptypes.Message) bool {
+	return isFromBancolombia(msg) && isStatement(msg)
+}
+
+func (b Bancolombia) ExtractStatementInfoFromMessage(msg imaptypes.Message) (*synctypes.StatementInfo, error) {
+	text := string(msg.RawBody)
+
+	account := common.ExtractFieldsStringWithRegexp(text, statementAccountRegexp)["account"]
+	if account == "" {
+		return nil, synctypes.NewMissingFieldError(b.Name(), "account")
+	}
+
+	balance, err := extractStatementAmount(b, text, statementBalanceRegexp, "balance")
+	if err != nil {
+		return nil, err
+	}
+
+	minimumPayment, err := extractStatementAmount(b, text, statementMinimumPaymentRegexp, "minimum payment")
+	if err != nil {
+		return nil, err
+	}
+
+	dueDate := common.ExtractFieldsStringWithRegexp(text, statementDueDateRegexp)["date"]
+	if dueDate == "" {
+		return nil, synctypes.NewMissingFieldError(b.Name(), "due date")
+	}
+	date, err := time.ParseInLocation(common.BodyDateLayout, dueDate, common.GetLocalLocation())
+	if err != nil {
+		return nil, synctypes.NewMissingFieldError(b.Name(), "due date")
+	}
+
+	return &synctypes.StatementInfo{
+		Bank:           b,
+		MsgId:          msg.SeqNum,
+		Account:        account,
+		Balance:        balance,
+		MinimumPayment: minimumPayment,
+		DueDate:        date,
+	}, nil
+}
+
+func extractStatementAmount(b Bancolombia, text string, exp *regexp.Regexp, field string) (synctypes.Money, error) {
+	result := common.ExtractFieldsStringWithRegexp(text, exp)
+	if result["value"] == "" {
+		return synctypes.Money{}, synctypes.NewMissingFieldError(b.Name(), field)
+	}
+
+	value, err := common.GetValueFromText(result["value"], result["currency"])
+	if err != nil {
+		return synctypes.Money{}, synctypes.NewBadAmountError(b.Name(), err)
+	}
+
+	return value, nil
+}
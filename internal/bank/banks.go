@@ -2,15 +2,38 @@ package bank
 
 import (
 	"github.com/Philanthropists/toshl-email-autosync/internal/bank/bancolombia"
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/daviplata"
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/davivienda"
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/declarative"
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/nequi"
 	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
 )
 
 var banks []types.BankDelegate
 
 func init() {
-	banks = []types.BankDelegate{bancolombia.Bancolombia{}}
+	banks = []types.BankDelegate{
+		bancolombia.Bancolombia{},
+		davivienda.Davivienda{},
+		nequi.Nequi{},
+		daviplata.Daviplata{},
+	}
 }
 
 func GetBanks() []types.BankDelegate {
 	return banks
 }
+
+func LoadDefinitions(dir string) ([]types.BankDelegate, error) {
+	definitions, err := declarative.LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var delegates []types.BankDelegate
+	for _, definition := range definitions {
+		delegates = append(delegates, definition)
+	}
+
+	return delegates, nil
+}
@@ -0,0 +1,197 @@
+// Package banktest runs bank delegates against real, anonymized, .eml messages and compares the results with
+// golden files. Run the tests with -update to regenerate the golden files after an intended change.
+package banktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap"
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the bank parsers")
+
+const (
+	messageExt = ".eml"
+	goldenExt  = ".golden.json"
+)
+
+type Transaction struct {
+	Type         string `json:"type"`
+	Place        string `json:"place"`
+	Value        string `json:"value"`
+	Currency     string `json:"currency"`
+	Account      string `json:"account"`
+	Card         string `json:"card,omitempty"`
+	Date         string `json:"date"`
+	DateSource   string `json:"dateSource"`
+	Direction    string `json:"direction"`
+	Counterpart  string `json:"counterpart,omitempty"`
+	Refund       bool   `json:"refund,omitempty"`
+	Installments int    `json:"installments,omitempty"`
+}
+
+type Statement struct {
+	Account        string `json:"account"`
+	Balance        string `json:"balance"`
+	MinimumPayment string `json:"minimumPayment"`
+	Currency       string `json:"currency"`
+	DueDate        string `json:"dueDate"`
+}
+
+// Result is what a single bank delegate produced for a message that it did not filter out
+type Result struct {
+	Bank        string       `json:"bank"`
+	Error       string       `json:"error,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Statement   *Statement   `json:"statement,omitempty"`
+}
+
+func newTransaction(t *synctypes.TransactionInfo) *Transaction {
+	return &Transaction{
+		Type:         t.Type,
+		Place:        t.Place,
+		Value:        t.Value.Decimal(),
+		Currency:     t.Value.Currency,
+		Account:      t.Account,
+		Card:         string(t.Card),
+		Date:         t.Date.Format(time.RFC3339),
+		DateSource:   string(t.DateSource),
+		Direction:    t.Direction.String(),
+		Counterpart:  t.Counterpart,
+		Refund:       t.Refund != nil,
+		Installments: t.Installments,
+	}
+}
+
+func newStatement(s *synctypes.StatementInfo) *Statement {
+	return &Statement{
+		Account:        s.Account,
+		Balance:        s.Balance.Decimal(),
+		MinimumPayment: s.MinimumPayment.Decimal(),
+		Currency:       s.Balance.Currency,
+		DueDate:        s.DueDate.Format(time.RFC3339),
+	}
+}
+
+// LoadMessage reads an .eml file and builds the message through the same path used for messages fetched from IMAP
+func LoadMessage(t *testing.T, path string) imaptypes.Message {
+	t.Helper()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read message [%s]: %s", path, err)
+	}
+
+	msg, err := imap.ParseMessage(1, raw)
+	if err != nil {
+		t.Fatalf("could not parse message [%s]: %s", path, err)
+	}
+
+	return msg
+}
+
+// Process runs every bank delegate that keeps the message, in order, either as a transaction or as a statement
+func Process(msg imaptypes.Message, banks []synctypes.BankDelegate) []Result {
+	results := []Result{}
+	for _, bank := range banks {
+		if statements, ok := bank.(synctypes.StatementDelegate); ok && statements.FilterStatementMessage(msg) {
+			result := Result{Bank: bank.Name()}
+			s, err := statements.ExtractStatementInfoFromMessage(msg)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Statement = newStatement(s)
+			}
+
+			results = append(results, result)
+		}
+
+		if !bank.FilterMessage(msg) {
+			continue
+		}
+
+		result := Result{Bank: bank.Name()}
+		t, err := bank.ExtractTransactionInfoFromMessage(msg)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Transaction = newTransaction(t)
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// RunGolden processes every .eml file found recursively inside dir, and compares the results with the
+// .golden.json file next to it
+func RunGolden(t *testing.T, dir string, banks []synctypes.BankDelegate) {
+	t.Helper()
+
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && strings.HasSuffix(path, messageExt) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not list messages in [%s]: %s", dir, err)
+	}
+
+	if len(paths) == 0 {
+		t.Fatalf("no %s messages found in [%s]", messageExt, dir)
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		path := path
+		name, _ := filepath.Rel(dir, path)
+		t.Run(name, func(t *testing.T) {
+			msg := LoadMessage(t, path)
+			results := Process(msg, banks)
+
+			got, err := json.MarshalIndent(results, "", "  ")
+			if err != nil {
+				t.Fatalf("could not marshal results: %s", err)
+			}
+			got = append(got, '\n')
+
+			goldenPath := strings.TrimSuffix(path, messageExt) + goldenExt
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+					t.Fatalf("could not update golden file [%s]: %s", goldenPath, err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+			if errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("golden file [%s] does not exist, run the tests with -update to create it", goldenPath)
+			}
+			if err != nil {
+				t.Fatalf("could not read golden file [%s]: %s", goldenPath, err)
+			}
+
+			if !bytes.Equal(got, expected) {
+				t.Errorf("results do not match golden file [%s]\ngot:\n%s\nexpected:\n%s", goldenPath, got, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package daviplata
+
+import (
+	"regexp"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/wallet"
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+type Daviplata struct {
+}
+
+var parser = wallet.Parser{
+	Sender: "daviplata@davivienda.com",
+	Cases: []wallet.MessageCase{
+		{
+			Keyword:    "usted envió",
+			Regexp:     regexp.MustCompile(`Usted (?P<type>envió) \$(?P<value>[0-9,\.]+) al DaviPlata (?P<place>` + wallet.PhoneExp + `) desde su DaviPlata (?P<account>` + wallet.PhoneExp + `)`),
+			PhonePlace: true,
+		},
+		{
+			Keyword: "con qr",
+			Regexp:  regexp.MustCompile(`Usted (?P<type>pagó) \$(?P<value>[0-9,\.]+) con QR a (?P<place>.+?) desde su DaviPlata (?P<account>` + wallet.PhoneExp + `)`),
+		},
+		{
+			Keyword:   "recarga",
+			Regexp:    regexp.MustCompile(`Usted recibió una (?P<type>recarga) de \$(?P<value>[0-9,\.]+) en su DaviPlata (?P<account>` + wallet.PhoneExp + `) desde (?P<place>.+?)\.`),
+			Direction: synctypes.Credit,
+		},
+	},
+}
+
+func (b Daviplata) Name() string {
+	return "Daviplata"
+}
+
+func (b Daviplata) FilterMessage(msg imaptypes.Message) bool {
+	return parser.FilterMessage(msg)
+}
+
+func (b Daviplata) ExtractTransactionInfoFromMessage(msg imaptypes.Message) (*synctypes.TransactionInfo, error) {
+	return parser.ExtractTransactionInfo(b, msg)
+}
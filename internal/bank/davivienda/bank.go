@@ -0,0 +1,129 @@
+package davivienda
+
+import (
+	"regexp"
+	"strings"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+const senderAddress = "notificaciones@davivienda.com"
+
+type Davivienda struct {
+}
+
+type messageCase struct {
+	keyword string
+	regexp  *regexp.Regexp
+	// accountPlace is set when the place is the account that received the money
+	accountPlace bool
+	cash         bool
+}
+
+// cases are checked in order, the keywords are specific enough to not overlap between them
+var messageCases = []messageCase{
+	{
+		keyword: "realizó una compra",
+		regexp:  regexp.MustCompile(`realizó una (?P<type>compra) por \$(?P<value>[0-9,\.]+) en (?P<place>.+?) con su tarjeta (?P<card>crédito|débito) terminada en (?P<account>\d{4})`),
+	},
+	{
+		keyword: "realizó un pago",
+		regexp:  regexp.MustCompile(`realizó un (?P<type>pago) por \$(?P<value>[0-9,\.]+) a (?P<place>.+?) desde su cuenta terminada en (?P<account>\d{4})`),
+	},
+	{
+		keyword:      "realizó una transferencia",
+		regexp:       regexp.MustCompile(`realizó una (?P<type>transferencia) por \$(?P<value>[0-9,\.]+) desde su cuenta terminada en (?P<account>\d{4}) a la cuenta (?P<place>\d{6,16})`),
+		accountPlace: true,
+	},
+	{
+		keyword: "realizó un retiro",
+		regexp:  regexp.MustCompile(`realizó un (?P<type>retiro) por \$(?P<value>[0-9,\.]+) en (?P<place>.+?) de su cuenta terminada en (?P<account>\d{4})`),
+		cash:    true,
+	},
+}
+
+func selectMessageCase(text string) (messageCase, bool) {
+	lowerCaseText := strings.ToLower(text)
+	for _, c := range messageCases {
+		if strings.Contains(lowerCaseText, c.keyword) {
+			return c, true
+		}
+	}
+
+	return messageCase{}, false
+}
+
+var cardTypes = map[string]synctypes.CardType{
+	"crédito": synctypes.CreditCard,
+	"débito":  synctypes.DebitCard,
+}
+
+func (b Davivienda) Name() string {
+	return "Davivienda"
+}
+
+func (b Davivienda) FilterMessage(msg imaptypes.Message) bool {
+	keep := true
+	keep = keep && msg.Message != nil
+	keep = keep && msg.Message.Envelope != nil
+	if keep {
+		keep = false
+		for _, address := range msg.Message.Envelope.From {
+			from := address.Address()
+			if from == senderAddress {
+				keep = true
+				break
+			}
+		}
+	}
+
+	if keep {
+		_, keep = selectMessageCase(string(msg.RawBody))
+	}
+
+	return keep
+}
+
+func (b Davivienda) ExtractTransactionInfoFromMessage(msg imaptypes.Message) (*synctypes.TransactionInfo, error) {
+	text := string(msg.RawBody)
+
+	selected, ok := selectMessageCase(text)
+	if !ok {
+		return nil, synctypes.NewNoTemplateMatchedError(b.Name())
+	}
+
+	result := common.ExtractFieldsStringWithRegexp(text, selected.regexp)
+
+	if missing := common.MissingRequiredFields(result); len(missing) > 0 {
+		return nil, synctypes.NewMissingFieldError(b.Name(), strings.Join(missing, ","))
+	}
+	value, err := common.GetValueFromText(result["value"], synctypes.DefaultCurrencyCode)
+	if err != nil {
+		return nil, synctypes.NewBadAmountError(b.Name(), err)
+	}
+
+	var counterpart string
+	if selected.accountPlace {
+		counterpart = result["place"]
+	}
+	if selected.cash {
+		counterpart = synctypes.CashCounterpart
+	}
+
+	date, dateSource := common.GetTransactionDate(text, msg.Envelope.Date)
+
+	return &synctypes.TransactionInfo{
+		Bank:        b,
+		MsgId:       msg.SeqNum,
+		Type:        result["type"],
+		Place:       result["place"],
+		Value:       value,
+		Account:     result["account"],
+		Card:        cardTypes[result["card"]],
+		Date:        date,
+		DateSource:  dateSource,
+		Counterpart: counterpart,
+	}, nil
+}
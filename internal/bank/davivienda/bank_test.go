@@ -0,0 +1,40 @@
+package davivienda
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/banktest"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	_imap "github.com/emersion/go-imap"
+)
+
+// the messages are shared with the golden tests of every bank
+var testdata = filepath.Join("..", "testdata", "davivienda")
+
+func TestFilterMessageOtherSender(t *testing.T) {
+	var bank Davivienda
+
+	msg := banktest.LoadMessage(t, filepath.Join(testdata, "compra.eml"))
+	msg.Envelope.From = []*_imap.Address{{MailboxName: "notificaciones", HostName: "otrobanco.com"}}
+
+	if bank.FilterMessage(msg) {
+		t.Errorf("message from another sender should be filtered out")
+	}
+}
+
+func TestExtractTransactionInfoFromMessageErrors(t *testing.T) {
+	var bank Davivienda
+
+	_, err := bank.ExtractTransactionInfoFromMessage(banktest.LoadMessage(t, filepath.Join(testdata, "publicidad.eml")))
+
+	var parseErr *synctypes.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a parse error, got [%v]", err)
+	}
+
+	if parseErr.Reason != synctypes.ReasonNoTemplateMatched || parseErr.Bank != "Davivienda" {
+		t.Errorf("got [%s], expected no template matched for Davivienda", parseErr)
+	}
+}
@@ -0,0 +1,129 @@
+package declarative
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+type Bank struct {
+	definition Definition
+}
+
+func (b Bank) Name() string {
+	return b.definition.Name
+}
+
+func (b Bank) FilterMessage(msg imaptypes.Message) bool {
+	keep := true
+	keep = keep && msg.Message != nil
+	keep = keep && msg.Message.Envelope != nil
+	if keep {
+		keep = false
+		for _, address := range msg.Message.Envelope.From {
+			from := strings.ToLower(address.Address())
+			for _, sender := range b.definition.Senders {
+				if from == strings.ToLower(sender) {
+					keep = true
+					break
+				}
+			}
+		}
+	}
+
+	if keep {
+		keep = b.selectTemplate(string(msg.RawBody)) != nil
+	}
+
+	return keep
+}
+
+// selectTemplate returns the first template, in definition order, whose keyword is present in the text
+func (b Bank) selectTemplate(text string) *Template {
+	lowerCaseText := strings.ToLower(text)
+	for i := range b.definition.Templates {
+		t := &b.definition.Templates[i]
+		if strings.Contains(lowerCaseText, strings.ToLower(t.Keyword)) {
+			return t
+		}
+	}
+
+	return nil
+}
+
+func (b Bank) ExtractTransactionInfoFromMessage(msg imaptypes.Message) (*synctypes.TransactionInfo, error) {
+	text := string(msg.RawBody)
+
+	template := b.selectTemplate(text)
+	if template == nil {
+		return nil, synctypes.NewNoTemplateMatchedError(b.Name())
+	}
+
+	result := common.ExtractFieldsStringWithRegexp(text, template.compiled)
+
+	if missing := common.MissingRequiredFields(result); len(missing) > 0 {
+		return nil, synctypes.NewMissingFieldError(b.Name(), strings.Join(missing, ","))
+	}
+
+	amount, err := b.normalizeAmount(result["value"])
+	if err != nil {
+		return nil, synctypes.NewBadAmountError(b.Name(), err)
+	}
+
+	direction := synctypes.Debit
+	if template.Sign == SignIncome {
+		direction = synctypes.Credit
+	}
+
+	code := b.definition.Currency
+	if template.Currency != "" {
+		code = template.Currency
+	}
+	if result["currency"] != "" {
+		code = strings.ToUpper(result["currency"])
+	}
+
+	value, err := synctypes.ParseMoney(code, amount)
+	if err != nil {
+		return nil, synctypes.NewBadAmountError(b.Name(), err)
+	}
+
+	date, dateSource := common.GetTransactionDateWithRegexps(text, b.definition.dateRegexps, msg.Envelope.Date)
+
+	return &synctypes.TransactionInfo{
+		Bank:        b,
+		MsgId:       msg.SeqNum,
+		Type:        result["type"],
+		Place:       result["place"],
+		Value:       value,
+		Account:     result["account"],
+		Date:        date,
+		DateSource:  dateSource,
+		Direction:   direction,
+		Counterpart: result["counterpart"],
+	}, nil
+}
+
+var amountRegexp = regexp.MustCompile(`^[0-9]+(?:\.[0-9]+)?$`)
+
+// normalizeAmount returns the amount as a plain decimal number, without thousands separator and with a dot as
+// decimal separator
+func (b Bank) normalizeAmount(s string) (string, error) {
+	locale := b.definition.Amount
+
+	cleaned := strings.TrimSpace(s)
+	if locale.ThousandsSeparator != "" {
+		cleaned = strings.ReplaceAll(cleaned, locale.ThousandsSeparator, "")
+	}
+	cleaned = strings.ReplaceAll(cleaned, locale.DecimalSeparator, ".")
+
+	if !amountRegexp.MatchString(cleaned) {
+		return "", fmt.Errorf("amount [%s] is not valid for bank definition [%s]", s, b.definition.Name)
+	}
+
+	return cleaned, nil
+}
@@ -0,0 +1,73 @@
+package declarative
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/banktest"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+func TestExtractTransactionInfoFromMessage(t *testing.T) {
+	bank, err := LoadFile("testdata/banks/banco-ejemplo.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var tests = []struct {
+		file      string
+		kind      string
+		place     string
+		value     string
+		account   string
+		date      time.Time
+		direction synctypes.Direction
+	}{
+		{
+			file:      "testdata/compra.eml",
+			kind:      "Compra",
+			place:     "TIENDA D1 CALLE 45",
+			value:     "13900.50",
+			account:   "4321",
+			date:      time.Date(2022, 3, 12, 21, 14, 0, 0, common.GetLocalLocation()),
+			direction: synctypes.Debit,
+		},
+		{
+			file:      "testdata/recibiste.eml",
+			kind:      "Recibiste",
+			place:     "EMPRESA SAS",
+			value:     "2500000.00",
+			account:   "8765",
+			date:      time.Date(2022, 3, 14, 8, 1, 0, 0, common.GetLocalLocation()),
+			direction: synctypes.Credit,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			msg := banktest.LoadMessage(t, test.file)
+			if !bank.FilterMessage(msg) {
+				t.Fatalf("expected the message to be kept")
+			}
+
+			tx, err := bank.ExtractTransactionInfoFromMessage(msg)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if tx.Type != test.kind || tx.Place != test.place || tx.Account != test.account {
+				t.Errorf("got type [%s], place [%s] and account [%s]", tx.Type, tx.Place, tx.Account)
+			}
+			if tx.Value.Decimal() != test.value || tx.Value.Currency != "COP" {
+				t.Errorf("got value %s %s, expected %s COP", tx.Value.Decimal(), tx.Value.Currency, test.value)
+			}
+			if !tx.Date.Equal(test.date) || tx.DateSource != synctypes.DateSourceBody {
+				t.Errorf("got date %s from %s, expected %s from the body", tx.Date, tx.DateSource, test.date)
+			}
+			if tx.Direction != test.direction {
+				t.Errorf("got direction %s, expected %s", tx.Direction, test.direction)
+			}
+		})
+	}
+}
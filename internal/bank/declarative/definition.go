@@ -0,0 +1,202 @@
+package declarative
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+)
+
+const (
+	SignExpense = "expense"
+	SignIncome  = "income"
+)
+
+type AmountLocale struct {
+	DecimalSeparator   string `json:"decimal-separator" yaml:"decimal-separator"`
+	ThousandsSeparator string `json:"thousands-separator" yaml:"thousands-separator"`
+}
+
+type Template struct {
+	Keyword  string `json:"keyword" yaml:"keyword"`
+	Regexp   string `json:"regexp" yaml:"regexp"`
+	Sign     string `json:"sign,omitempty" yaml:"sign,omitempty"`
+	Currency string `json:"currency,omitempty" yaml:"currency,omitempty"`
+
+	compiled *regexp.Regexp
+}
+
+type Definition struct {
+	Name      string       `json:"name" yaml:"name"`
+	Senders   []string     `json:"senders" yaml:"senders"`
+	Currency  string       `json:"currency" yaml:"currency"`
+	Amount    AmountLocale `json:"amount" yaml:"amount"`
+	Templates []Template   `json:"templates" yaml:"templates"`
+	// DateRegexps match the transaction date inside the message, they need a "date" named group formatted
+	// as dd/mm/yyyy and optionally a "time" named group formatted as hh:mm
+	DateRegexps []string `json:"date-regexps,omitempty" yaml:"date-regexps,omitempty"`
+
+	dateRegexps []*regexp.Regexp
+}
+
+var currencyCodeRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+var requiredGroups = []string{"value", "type", "place", "account"}
+
+// Validate checks the definition and compiles its templates, it must be called before
+// the definition is used to parse messages
+func (d *Definition) Validate() error {
+	var problems []string
+
+	if d.Name == "" {
+		problems = append(problems, "name cannot be empty")
+	}
+
+	if len(d.Senders) == 0 {
+		problems = append(problems, "at least one sender is required")
+	}
+
+	if !currencyCodeRegexp.MatchString(d.Currency) {
+		problems = append(problems, fmt.Sprintf("currency [%s] is not an ISO 4217 code", d.Currency))
+	}
+
+	if d.Amount.DecimalSeparator == "" {
+		problems = append(problems, "amount decimal-separator cannot be empty")
+	}
+
+	if d.Amount.DecimalSeparator == d.Amount.ThousandsSeparator {
+		problems = append(problems, "amount decimal-separator and thousands-separator must be different")
+	}
+
+	if len(d.Templates) == 0 {
+		problems = append(problems, "at least one template is required")
+	}
+
+	d.dateRegexps = nil
+	for i, exp := range d.DateRegexps {
+		compiled, err := regexp.Compile(exp)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("date regexp %d does not compile: %s", i, err))
+			continue
+		}
+
+		if compiled.SubexpIndex("date") < 0 {
+			problems = append(problems, fmt.Sprintf("date regexp %d is missing named group [date]", i))
+			continue
+		}
+
+		d.dateRegexps = append(d.dateRegexps, compiled)
+	}
+
+	if len(d.DateRegexps) == 0 {
+		d.dateRegexps = common.DefaultBodyDateRegexps
+	}
+
+	for i := range d.Templates {
+		t := &d.Templates[i]
+		for _, problem := range t.validate() {
+			problems = append(problems, fmt.Sprintf("template %d (%s): %s", i, t.Keyword, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid bank definition [%s]: %s", d.Name, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func (t *Template) validate() []string {
+	var problems []string
+
+	if t.Keyword == "" {
+		problems = append(problems, "keyword cannot be empty")
+	}
+
+	switch t.Sign {
+	case "":
+		t.Sign = SignExpense
+	case SignExpense, SignIncome:
+	default:
+		problems = append(problems, fmt.Sprintf("sign [%s] should be one of [%s, %s]", t.Sign, SignExpense, SignIncome))
+	}
+
+	if t.Currency != "" && !currencyCodeRegexp.MatchString(t.Currency) {
+		problems = append(problems, fmt.Sprintf("currency [%s] is not an ISO 4217 code", t.Currency))
+	}
+
+	compiled, err := regexp.Compile(t.Regexp)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("regexp does not compile: %s", err))
+		return problems
+	}
+
+	groups := map[string]struct{}{}
+	for _, name := range compiled.SubexpNames() {
+		groups[name] = struct{}{}
+	}
+
+	for _, group := range requiredGroups {
+		if _, ok := groups[group]; !ok {
+			problems = append(problems, fmt.Sprintf("regexp is missing named group [%s]", group))
+		}
+	}
+
+	t.compiled = compiled
+
+	return problems
+}
+
+func LoadFile(path string) (*Bank, error) {
+	var def Definition
+	if err := common.DecodeConfigFile(path, &def); err != nil {
+		return nil, fmt.Errorf("could not load bank definition file [%s]: %w", path, err)
+	}
+
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &Bank{definition: def}, nil
+}
+
+// LoadDir loads every definition file inside dir. All definitions are checked, and if any of them
+// is invalid an error describing every invalid file is returned
+func LoadDir(dir string) ([]*Bank, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var banks []*Bank
+	var problems []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+
+		bank, err := LoadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		banks = append(banks, bank)
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "\n"))
+	}
+
+	return banks, nil
+}
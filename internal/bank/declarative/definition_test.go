@@ -0,0 +1,139 @@
+package declarative
+
+import (
+	"strings"
+	"testing"
+)
+
+func validDefinition() Definition {
+	return Definition{
+		Name:     "Banco Ejemplo",
+		Senders:  []string{"alertas@bancoejemplo.com.co"},
+		Currency: "COP",
+		Amount:   AmountLocale{DecimalSeparator: ",", ThousandsSeparator: "."},
+		Templates: []Template{{
+			Keyword: "compra",
+			Regexp:  `(?P<type>Compra) por \$(?P<value>[0-9.,]+) en (?P<place>.+?) con tu tarjeta \*(?P<account>\d{4})`,
+		}},
+	}
+}
+
+func TestValidateInvalidDefinitions(t *testing.T) {
+	var tests = []struct {
+		name    string
+		change  func(d *Definition)
+		problem string
+	}{
+		{
+			name:    "empty name",
+			change:  func(d *Definition) { d.Name = "" },
+			problem: "name cannot be empty",
+		},
+		{
+			name:    "no senders",
+			change:  func(d *Definition) { d.Senders = nil },
+			problem: "at least one sender is required",
+		},
+		{
+			name:    "bad currency",
+			change:  func(d *Definition) { d.Currency = "pesos" },
+			problem: "currency [pesos] is not an ISO 4217 code",
+		},
+		{
+			name:    "same separators",
+			change:  func(d *Definition) { d.Amount.ThousandsSeparator = "," },
+			problem: "decimal-separator and thousands-separator must be different",
+		},
+		{
+			name:    "no templates",
+			change:  func(d *Definition) { d.Templates = nil },
+			problem: "at least one template is required",
+		},
+		{
+			name:    "date regexp without date group",
+			change:  func(d *Definition) { d.DateRegexps = []string{`\d{2}/\d{2}/\d{4}`} },
+			problem: "date regexp 0 is missing named group [date]",
+		},
+		{
+			name:    "template regexp does not compile",
+			change:  func(d *Definition) { d.Templates[0].Regexp = `(?P<value>` },
+			problem: "template 0 (compra): regexp does not compile",
+		},
+		{
+			name:    "template regexp without account group",
+			change:  func(d *Definition) { d.Templates[0].Regexp = `(?P<type>Compra) (?P<value>\d+) (?P<place>.+)` },
+			problem: "template 0 (compra): regexp is missing named group [account]",
+		},
+		{
+			name:    "template with unknown sign",
+			change:  func(d *Definition) { d.Templates[0].Sign = "refund" },
+			problem: "sign [refund] should be one of [expense, income]",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			def := validDefinition()
+			test.change(&def)
+
+			err := def.Validate()
+			if err == nil {
+				t.Fatalf("expected an error containing [%s]", test.problem)
+			}
+			if !strings.Contains(err.Error(), test.problem) {
+				t.Errorf("expected an error containing [%s], got [%s]", test.problem, err)
+			}
+		})
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	def := validDefinition()
+	if err := def.Validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if def.Templates[0].Sign != SignExpense {
+		t.Errorf("expected templates without sign to be expenses, got [%s]", def.Templates[0].Sign)
+	}
+	if len(def.dateRegexps) == 0 {
+		t.Errorf("expected the default date regexps when none are given")
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	banks, err := LoadDir("testdata/banks")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(banks) != 1 || banks[0].Name() != "Banco Ejemplo" {
+		t.Errorf("expected the Banco Ejemplo definition, got %+v", banks)
+	}
+}
+
+func TestLoadDirInvalidDefinition(t *testing.T) {
+	banks, err := LoadDir("testdata/invalid")
+	if err == nil {
+		t.Fatalf("expected an error, got %d banks", len(banks))
+	}
+
+	expected := []string{
+		"sin-plantillas.json",
+		"at least one template is required",
+		"banco-ejemplo.yaml",
+		"template 0 (recibiste): regexp does not compile",
+		"template 1 (compra): regexp is missing named group [account]",
+	}
+	for _, e := range expected {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("expected the error to name every invalid file and its problems, [%s] is missing from [%s]", e, err)
+		}
+	}
+}
+
+func TestLoadDirMissing(t *testing.T) {
+	if _, err := LoadDir("testdata/missing"); err == nil {
+		t.Errorf("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,12 @@
+package bank_test
+
+import (
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank"
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/banktest"
+)
+
+func TestBanksGolden(t *testing.T) {
+	banktest.RunGolden(t, "testdata", bank.GetBanks())
+}
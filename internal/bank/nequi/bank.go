@@ -0,0 +1,44 @@
+package nequi
+
+import (
+	"regexp"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/wallet"
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+type Nequi struct {
+}
+
+var parser = wallet.Parser{
+	Sender: "notificaciones@nequi.com.co",
+	Cases: []wallet.MessageCase{
+		{
+			Keyword:    "enviaste",
+			Regexp:     regexp.MustCompile(`(?P<type>Enviaste) \$(?P<value>[0-9,\.]+) desde tu Nequi (?P<account>` + wallet.PhoneExp + `) a (?:la cuenta Nequi |el celular )?(?P<place>` + wallet.PhoneExp + `)`),
+			PhonePlace: true,
+		},
+		{
+			Keyword: "código qr",
+			Regexp:  regexp.MustCompile(`(?P<type>Pagaste) \$(?P<value>[0-9,\.]+) con código QR en (?P<place>.+?) desde tu Nequi (?P<account>` + wallet.PhoneExp + `)`),
+		},
+		{
+			Keyword:   "recargaste",
+			Regexp:    regexp.MustCompile(`(?P<type>Recargaste) \$(?P<value>[0-9,\.]+) en tu Nequi (?P<account>` + wallet.PhoneExp + `) desde (?P<place>.+?)\.`),
+			Direction: synctypes.Credit,
+		},
+	},
+}
+
+func (b Nequi) Name() string {
+	return "Nequi"
+}
+
+func (b Nequi) FilterMessage(msg imaptypes.Message) bool {
+	return parser.FilterMessage(msg)
+}
+
+func (b Nequi) ExtractTransactionInfoFromMessage(msg imaptypes.Message) (*synctypes.TransactionInfo, error) {
+	return parser.ExtractTransactionInfo(b, msg)
+}
@@ -0,0 +1,104 @@
+// Package wallet parses the notifications of mobile wallets, e.g. Nequi and Daviplata, whose accounts are phone
+// numbers and whose messages only differ in their wording
+package wallet
+
+import (
+	"regexp"
+	"strings"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+// PhoneExp matches a colombian mobile number, with or without the country code and spaces
+const PhoneExp = `(?:\+?57\s?)?3\d{2}\s?\d{3}\s?\d{4}`
+
+type MessageCase struct {
+	Keyword   string
+	Regexp    *regexp.Regexp
+	Direction synctypes.Direction
+	// PhonePlace means the place is the phone number of another wallet, which is also the counterpart
+	PhonePlace bool
+}
+
+// Parser keeps the messages of the sender that match one of its cases, the first case whose keyword is
+// present in the message is used
+type Parser struct {
+	Sender string
+	Cases  []MessageCase
+}
+
+func (p Parser) selectMessageCase(text string) (MessageCase, bool) {
+	lowerCaseText := strings.ToLower(text)
+	for _, c := range p.Cases {
+		if strings.Contains(lowerCaseText, c.Keyword) {
+			return c, true
+		}
+	}
+
+	return MessageCase{}, false
+}
+
+func (p Parser) FilterMessage(msg imaptypes.Message) bool {
+	keep := true
+	keep = keep && msg.Message != nil
+	keep = keep && msg.Message.Envelope != nil
+	if keep {
+		keep = false
+		for _, address := range msg.Message.Envelope.From {
+			if address.Address() == p.Sender {
+				keep = true
+				break
+			}
+		}
+	}
+
+	if keep {
+		_, keep = p.selectMessageCase(string(msg.RawBody))
+	}
+
+	return keep
+}
+
+// ExtractTransactionInfo parses the message on behalf of the bank, which is the one set in the transaction
+func (p Parser) ExtractTransactionInfo(bank synctypes.BankDelegate, msg imaptypes.Message) (*synctypes.TransactionInfo, error) {
+	text := string(msg.RawBody)
+
+	selected, ok := p.selectMessageCase(text)
+	if !ok {
+		return nil, synctypes.NewNoTemplateMatchedError(bank.Name())
+	}
+
+	result := common.ExtractFieldsStringWithRegexp(text, selected.Regexp)
+
+	if missing := common.MissingRequiredFields(result); len(missing) > 0 {
+		return nil, synctypes.NewMissingFieldError(bank.Name(), strings.Join(missing, ","))
+	}
+	value, err := common.GetValueFromText(result["value"], synctypes.DefaultCurrencyCode)
+	if err != nil {
+		return nil, synctypes.NewBadAmountError(bank.Name(), err)
+	}
+
+	var counterpart string
+	place := result["place"]
+	if selected.PhonePlace {
+		place = common.NormalizePhoneNumber(place)
+		counterpart = place
+	}
+
+	date, dateSource := common.GetTransactionDate(text, msg.Envelope.Date)
+
+	return &synctypes.TransactionInfo{
+		Bank:        bank,
+		MsgId:       msg.SeqNum,
+		Type:        strings.ToLower(result["type"]),
+		Place:       place,
+		Value:       value,
+		Account:     common.NormalizePhoneNumber(result["account"]),
+		Date:        date,
+		DateSource:  dateSource,
+		Direction:   selected.Direction,
+		Counterpart: counterpart,
+	}, nil
+}
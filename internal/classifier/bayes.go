@@ -0,0 +1,82 @@
+package classifier
+
+import (
+	"math"
+)
+
+// NaiveBayes is a multinomial naive Bayes classifier over tokens, exported fields are persisted
+type NaiveBayes struct {
+	Docs    int               `json:"docs"`
+	Classes map[string]*Class `json:"classes"`
+	// Vocabulary counts how many classes have seen each token
+	Vocabulary map[string]int `json:"vocabulary"`
+}
+
+// Class is the token frequencies of the documents of a class
+type Class struct {
+	Docs   int            `json:"docs"`
+	Tokens map[string]int `json:"tokens"`
+	Total  int            `json:"total"`
+}
+
+func NewNaiveBayes() *NaiveBayes {
+	return &NaiveBayes{
+		Classes:    make(map[string]*Class),
+		Vocabulary: make(map[string]int),
+	}
+}
+
+// Add trains the classifier with a document of the class
+func (nb *NaiveBayes) Add(class string, tokens []string) {
+	c, ok := nb.Classes[class]
+	if !ok {
+		c = &Class{Tokens: make(map[string]int)}
+		nb.Classes[class] = c
+	}
+
+	nb.Docs++
+	c.Docs++
+	for _, token := range tokens {
+		if c.Tokens[token] == 0 {
+			nb.Vocabulary[token]++
+		}
+		c.Tokens[token]++
+		c.Total++
+	}
+}
+
+// Predict returns the most probable class of the document and its posterior probability, tokens that
+// were never seen are ignored
+func (nb *NaiveBayes) Predict(tokens []string) (string, float64) {
+	if nb == nil || nb.Docs == 0 {
+		return "", 0
+	}
+
+	vocabulary := float64(len(nb.Vocabulary))
+
+	scores := make(map[string]float64, len(nb.Classes))
+	best, bestScore := "", math.Inf(-1)
+	for name, c := range nb.Classes {
+		score := math.Log(float64(c.Docs) / float64(nb.Docs))
+		for _, token := range tokens {
+			if nb.Vocabulary[token] == 0 {
+				continue
+			}
+			// Laplace smoothing
+			score += math.Log((float64(c.Tokens[token]) + 1) / (float64(c.Total) + vocabulary))
+		}
+
+		scores[name] = score
+		if score > bestScore || (score == bestScore && name < best) {
+			best, bestScore = name, score
+		}
+	}
+
+	// posterior of the best class, normalized with log-sum-exp
+	var sum float64
+	for _, score := range scores {
+		sum += math.Exp(score - bestScore)
+	}
+
+	return best, 1 / sum
+}
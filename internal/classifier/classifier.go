@@ -0,0 +1,148 @@
+package classifier
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+const (
+	ExpenseType = "expense"
+	IncomeType  = "income"
+
+	// minTagExamples is how many entries must have a tag for it to be learned
+	minTagExamples = 3
+
+	tagged   = "tagged"
+	untagged = "untagged"
+)
+
+// Example is an entry already categorized by hand
+type Example struct {
+	// Type is the type of the category, expense or income
+	Type        string
+	Description string
+	Amount      types.Money
+	Category    string
+	Tags        []string
+}
+
+// Model predicts the category and tags of an entry, it is persisted as JSON
+type Model struct {
+	TrainedAt time.Time `json:"trained-at"`
+	// Categories has a classifier by category type, so expenses only get expense categories
+	Categories map[string]*NaiveBayes `json:"categories"`
+	// Tags has a classifier by tag that tells if the entry has it
+	Tags map[string]*NaiveBayes `json:"tags"`
+}
+
+// Prediction is the category and tags that were predicted with enough confidence
+type Prediction struct {
+	Category   string
+	Confidence float64
+	Tags       []string
+}
+
+var tokenRegexp = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+var stopWords = map[string]bool{
+	"de": true, "del": true, "la": true, "el": true, "los": true, "las": true,
+	"en": true, "por": true, "y": true, "a": true, "con": true,
+}
+
+// Tokenize splits the description in lower case words, and adds a token for the order of magnitude of the
+// amount so that small and large payments to the same place can be told apart
+func Tokenize(description string, amount types.Money) []string {
+	var tokens []string
+	for _, word := range tokenRegexp.FindAllString(strings.ToLower(description), -1) {
+		if len(word) < 2 || stopWords[word] {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+
+	if value := math.Abs(amount.Float64()); value > 0 {
+		// two buckets per order of magnitude, e.g. 10.000 to 31.622 and 31.623 to 99.999
+		bucket := int(math.Floor(2 * math.Log10(value)))
+		tokens = append(tokens, "amount:"+strconv.Itoa(bucket))
+	}
+
+	return tokens
+}
+
+// Train builds a model with the examples
+func Train(examples []Example) *Model {
+	model := &Model{
+		TrainedAt:  time.Now(),
+		Categories: make(map[string]*NaiveBayes),
+		Tags:       make(map[string]*NaiveBayes),
+	}
+
+	tagCount := make(map[string]int)
+	for _, e := range examples {
+		for _, tag := range e.Tags {
+			tagCount[tag]++
+		}
+	}
+
+	for tag, count := range tagCount {
+		if count >= minTagExamples {
+			model.Tags[tag] = NewNaiveBayes()
+		}
+	}
+
+	for _, e := range examples {
+		tokens := Tokenize(e.Description, e.Amount)
+
+		categories, ok := model.Categories[e.Type]
+		if !ok {
+			categories = NewNaiveBayes()
+			model.Categories[e.Type] = categories
+		}
+		categories.Add(e.Category, tokens)
+
+		hasTag := make(map[string]bool)
+		for _, tag := range e.Tags {
+			hasTag[tag] = true
+		}
+		for tag, nb := range model.Tags {
+			if hasTag[tag] {
+				nb.Add(tagged, tokens)
+			} else {
+				nb.Add(untagged, tokens)
+			}
+		}
+	}
+
+	return model
+}
+
+// Predict returns the category of the given type and the tags of the entry, when their probability is at
+// least the threshold
+func (m *Model) Predict(categoryType, description string, amount types.Money, threshold float64) (Prediction, bool) {
+	tokens := Tokenize(description, amount)
+
+	category, confidence := m.Categories[categoryType].Predict(tokens)
+	if category == "" || confidence < threshold {
+		return Prediction{}, false
+	}
+
+	prediction := Prediction{
+		Category:   category,
+		Confidence: confidence,
+	}
+
+	for tag, nb := range m.Tags {
+		if class, p := nb.Predict(tokens); class == tagged && p >= threshold {
+			prediction.Tags = append(prediction.Tags, tag)
+		}
+	}
+	sort.Strings(prediction.Tags)
+
+	return prediction, true
+}
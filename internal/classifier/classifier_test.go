@@ -0,0 +1,78 @@
+package classifier
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+func cop(value string) types.Money {
+	money, err := types.ParseMoney("COP", value)
+	if err != nil {
+		panic(err)
+	}
+	return money
+}
+
+func trainingExamples() []Example {
+	var examples []Example
+	add := func(n int, e Example) {
+		for i := 0; i < n; i++ {
+			examples = append(examples, e)
+		}
+	}
+
+	add(6, Example{Type: ExpenseType, Description: "** Compra de Netflix", Amount: cop("38900"), Category: "Suscripciones", Tags: []string{"streaming"}})
+	add(6, Example{Type: ExpenseType, Description: "** Compra de Spotify", Amount: cop("16900"), Category: "Suscripciones", Tags: []string{"streaming"}})
+	add(8, Example{Type: ExpenseType, Description: "** Compra de Éxito", Amount: cop("185000"), Category: "Mercado"})
+	add(3, Example{Type: ExpenseType, Description: "** Compra de Éxito", Amount: cop("12000"), Category: "Snacks"})
+	add(5, Example{Type: IncomeType, Description: "** Transferencia de EMPRESA SAS", Amount: cop("5000000"), Category: "Salario"})
+
+	return examples
+}
+
+func TestPredict(t *testing.T) {
+	model := Train(trainingExamples())
+
+	prediction, ok := model.Predict(ExpenseType, "** Compra de Netflix", cop("38900"), 0.8)
+	if !ok || prediction.Category != "Suscripciones" {
+		t.Fatalf("expected Suscripciones, got %+v", prediction)
+	}
+	if len(prediction.Tags) != 1 || prediction.Tags[0] != "streaming" {
+		t.Errorf("expected the streaming tag, got %v", prediction.Tags)
+	}
+
+	prediction, ok = model.Predict(ExpenseType, "** Compra de Éxito", cop("210000"), 0.8)
+	if !ok || prediction.Category != "Mercado" || len(prediction.Tags) != 0 {
+		t.Errorf("expected Mercado without tags, got %+v", prediction)
+	}
+
+	// income categories are never predicted for expenses
+	if prediction, ok := model.Predict(ExpenseType, "** Transferencia de EMPRESA SAS", cop("5000000"), 0.8); ok && prediction.Category == "Salario" {
+		t.Errorf("predicted an income category for an expense")
+	}
+
+	if prediction, ok := model.Predict(ExpenseType, "** Compra de PANADERIA", cop("5000"), 0.8); ok {
+		t.Errorf("expected no prediction for an unknown place, got %+v", prediction)
+	}
+}
+
+func TestModelRoundTrip(t *testing.T) {
+	model := Train(trainingExamples())
+
+	raw, err := json.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded Model
+	if err := json.Unmarshal(raw, &loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	prediction, ok := loaded.Predict(ExpenseType, "** Compra de Spotify", cop("16900"), 0.8)
+	if !ok || prediction.Category != "Suscripciones" {
+		t.Errorf("expected Suscripciones from the loaded model, got %+v", prediction)
+	}
+}
@@ -0,0 +1,44 @@
+package imap
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlIgnoredElementsRegexp = regexp.MustCompile(`(?is)<!--.*?-->|<(script|style|head|title)\b.*?</(script|style|head|title)\s*>`)
+	htmlLineBreakTagsRegexp   = regexp.MustCompile(`(?i)<(br|/?p|/?div|/?tr|/?li|/?ul|/?ol|/?table|/?h[1-6]|/?blockquote|hr)\b[^>]*>`)
+	htmlCellTagsRegexp        = regexp.MustCompile(`(?i)</?t[dh]\b[^>]*>`)
+	htmlTagsRegexp            = regexp.MustCompile(`(?s)<[^>]*>`)
+	horizontalSpaceRegexp     = regexp.MustCompile(`[\t\f\v \x{00a0}\x{2007}\x{202f}\x{200b}]+`)
+)
+
+// htmlToText converts an HTML document into plain text, keeping line breaks where block elements were
+func htmlToText(s string) string {
+	s = htmlIgnoredElementsRegexp.ReplaceAllString(s, "")
+	s = htmlLineBreakTagsRegexp.ReplaceAllString(s, "\n")
+	s = htmlCellTagsRegexp.ReplaceAllString(s, " ")
+	s = htmlTagsRegexp.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	return normalizeText(s)
+}
+
+// normalizeText collapses horizontal whitespace (including non-breaking spaces) into single spaces,
+// trims every line and removes empty lines
+func normalizeText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = horizontalSpaceRegexp.ReplaceAllString(line, " ")
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
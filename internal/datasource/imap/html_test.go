@@ -0,0 +1,40 @@
+package imap
+
+import "testing"
+
+func TestHtmlToText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "entities and non-breaking spaces",
+			input:    "<p>Compra&nbsp;por&nbsp;$13.900,00 en CAF&Eacute; &amp; T&Eacute;</p>",
+			expected: "Compra por $13.900,00 en CAFÉ & TÉ",
+		},
+		{
+			name:     "ignored elements",
+			input:    "<html><head><title>Alerta</title><style>p {}</style></head><body><!-- tracking --><script>x()</script>Hola</body></html>",
+			expected: "Hola",
+		},
+		{
+			name:     "blocks and cells",
+			input:    "<table><tr><td>Valor:</td><td>$10.000</td></tr><tr><td>Cuenta:</td><td>*1234</td></tr></table>",
+			expected: "Valor: $10.000\nCuenta: *1234",
+		},
+		{
+			name:     "collapsed whitespace",
+			input:    "<div>\n\t  Bancolombia   le\n informa  </div><br/><br>",
+			expected: "Bancolombia le\ninforma",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(tt.input); got != tt.expected {
+				t.Errorf("got [%q], expected [%q]", got, tt.expected)
+			}
+		})
+	}
+}
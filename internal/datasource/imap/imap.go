@@ -11,6 +11,8 @@ import (
 	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
 	_imap "github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-message"
+	_ "github.com/emersion/go-message/charset"
 	"github.com/emersion/go-message/mail"
 )
 
@@ -90,7 +92,7 @@ func (m mailClientImpl) GetMessages(mailbox types.Mailbox, since time.Time, filt
 	done := make(chan error, 1)
 
 	var section _imap.BodySectionName
-	items := []_imap.FetchItem{section.FetchItem(), _imap.FetchEnvelope}
+	items := []_imap.FetchItem{section.FetchItem(), _imap.FetchEnvelope, _imap.FetchUid}
 	go func() {
 		done <- m.client.Fetch(seqset, items, messages)
 	}()
@@ -103,6 +105,7 @@ func (m mailClientImpl) GetMessages(mailbox types.Mailbox, since time.Time, filt
 
 	var filteredMsgs []types.Message
 	for msg := range filteredMsgsChan {
+		msg.UIDValidity = boxStatus.UidValidity
 		filteredMsgs = append(filteredMsgs, msg)
 	}
 
@@ -143,41 +146,99 @@ func processMessages(messages <-chan *_imap.Message, filter types.Filter, outCha
 }
 
 func getCompleteMessage(_msg *_imap.Message) (types.Message, error) {
-	body, err := getMessageBody(_msg)
+	body, attachments, err := getMessageParts(_msg)
 	if err != nil {
 		return types.Message{}, err
 	}
 
 	return types.Message{
-		Message: _msg,
-		RawBody: body,
+		Message:     _msg,
+		RawBody:     body,
+		Attachments: attachments,
 	}, nil
 }
 
-func getMessageBody(_msg *_imap.Message) ([]byte, error) {
+// getMessageParts returns the text of the message and its attachments, messages that only have attachments get
+// an empty body
+func getMessageParts(_msg *_imap.Message) ([]byte, []types.Attachment, error) {
 	var section _imap.BodySectionName
 	t := _msg.GetBody(&section)
-	mr, _ := mail.CreateReader(t)
+	if t == nil {
+		return nil, nil, errors.New("no body found in msg")
+	}
+
+	mr, err := mail.CreateReader(t)
+	if err != nil && !message.IsUnknownCharset(err) {
+		return nil, nil, err
+	}
 
-	var body []byte
-	for body == nil {
+	var plainBody, htmlBody, otherBody []byte
+	var attachments []types.Attachment
+	for {
 		p, err := mr.NextPart()
 		if err == io.EOF {
 			break
+		} else if err != nil && !message.IsUnknownCharset(err) {
+			return nil, nil, err
+		}
+
+		if h, ok := p.Header.(*mail.AttachmentHeader); ok {
+			if attachment, err := getAttachment(h, p.Body); err == nil {
+				attachments = append(attachments, attachment)
+			}
+			continue
+		}
+
+		h, ok := p.Header.(*mail.InlineHeader)
+		if !ok {
+			continue
+		}
+
+		// This is the message's text (can be plain-text or HTML)
+		body, err := ioutil.ReadAll(p.Body)
+		if err != nil {
+			continue
 		}
 
-		switch p.Header.(type) {
-		case *mail.InlineHeader:
-			// This is the message's text (can be plain-text or HTML)
-			body, _ = ioutil.ReadAll(p.Body)
+		contentType, _, _ := h.ContentType()
+		switch {
+		case contentType == "text/plain" && plainBody == nil:
+			plainBody = body
+		case contentType == "text/html" && htmlBody == nil:
+			htmlBody = body
+		case otherBody == nil:
+			otherBody = body
 		}
 	}
 
-	if body == nil {
-		return nil, errors.New("no body found in msg")
+	switch {
+	case plainBody != nil:
+		return []byte(normalizeText(string(plainBody))), attachments, nil
+	case htmlBody != nil:
+		return []byte(htmlToText(string(htmlBody))), attachments, nil
+	case otherBody != nil:
+		return otherBody, attachments, nil
+	case len(attachments) > 0:
+		return []byte{}, attachments, nil
+	}
+
+	return nil, nil, errors.New("no body found in msg")
+}
+
+func getAttachment(h *mail.AttachmentHeader, body io.Reader) (types.Attachment, error) {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return types.Attachment{}, err
 	}
 
-	return body, nil
+	filename, _ := h.Filename()
+	contentType, _, _ := h.ContentType()
+
+	return types.Attachment{
+		Filename:    filename,
+		ContentType: contentType,
+		Data:        data,
+	}, nil
 }
 
 func (m mailClientImpl) Move(ids []uint32, destMailbox types.Mailbox) error {
@@ -0,0 +1,92 @@
+package imap
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	_imap "github.com/emersion/go-imap"
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+)
+
+// ParseMessage builds a message from its raw RFC 5322 representation (e.g. an .eml file), the body is
+// extracted in the same way as it is for messages fetched from the server
+func ParseMessage(seqNum uint32, raw []byte) (types.Message, error) {
+	mr, err := mail.CreateReader(bytes.NewReader(raw))
+	if err != nil && !message.IsUnknownCharset(err) {
+		return types.Message{}, err
+	}
+	defer mr.Close()
+
+	envelope, err := getEnvelope(mr.Header)
+	if err != nil {
+		return types.Message{}, err
+	}
+
+	var section _imap.BodySectionName
+	_msg := &_imap.Message{
+		SeqNum:   seqNum,
+		Envelope: envelope,
+		Body: map[*_imap.BodySectionName]_imap.Literal{
+			&section: bytes.NewReader(raw),
+		},
+	}
+
+	return getCompleteMessage(_msg)
+}
+
+func getEnvelope(header mail.Header) (*_imap.Envelope, error) {
+	date, err := header.Date()
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := header.Subject()
+	if err != nil {
+		return nil, err
+	}
+
+	messageId, err := header.MessageID()
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &_imap.Envelope{
+		Date:      date,
+		Subject:   subject,
+		MessageId: messageId,
+	}
+
+	for key, addresses := range map[string]*[]*_imap.Address{
+		"From":     &envelope.From,
+		"Sender":   &envelope.Sender,
+		"Reply-To": &envelope.ReplyTo,
+		"To":       &envelope.To,
+		"Cc":       &envelope.Cc,
+	} {
+		list, err := header.AddressList(key)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, address := range list {
+			*addresses = append(*addresses, toImapAddress(address))
+		}
+	}
+
+	return envelope, nil
+}
+
+func toImapAddress(address *mail.Address) *_imap.Address {
+	mailbox, host := address.Address, ""
+	if i := strings.LastIndex(address.Address, "@"); i >= 0 {
+		mailbox, host = address.Address[:i], address.Address[i+1:]
+	}
+
+	return &_imap.Address{
+		PersonalName: address.Name,
+		MailboxName:  mailbox,
+		HostName:     host,
+	}
+}
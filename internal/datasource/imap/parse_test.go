@@ -0,0 +1,46 @@
+package imap
+
+import (
+	"strings"
+	"testing"
+)
+
+const messageWithAttachment = "From: Banco <extractos@example.com>\r\n" +
+	"To: cliente@example.com\r\n" +
+	"Subject: Extracto\r\n" +
+	"Date: Tue, 01 Mar 2022 08:00:00 -0500\r\n" +
+	"Message-ID: <extracto@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"b\"\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/plain; charset=\"utf-8\"\r\n" +
+	"\r\n" +
+	"Adjuntamos su extracto.\r\n" +
+	"--b\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=\"extracto.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQ=\r\n" +
+	"--b--\r\n"
+
+func TestParseMessageAttachments(t *testing.T) {
+	msg, err := ParseMessage(1, []byte(messageWithAttachment))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if body := strings.TrimSpace(string(msg.RawBody)); body != "Adjuntamos su extracto." {
+		t.Errorf("got body [%s]", body)
+	}
+
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("got %d attachments, expected 1", len(msg.Attachments))
+	}
+
+	attachment := msg.Attachments[0]
+	if attachment.Filename != "extracto.pdf" || attachment.ContentType != "application/pdf" || string(attachment.Data) != "%PDF-1.4" {
+		t.Errorf("got attachment [%s %s %q]", attachment.Filename, attachment.ContentType, attachment.Data)
+	}
+}
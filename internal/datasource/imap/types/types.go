@@ -10,6 +10,16 @@ type Message struct {
 	*_imap.Message
 
 	RawBody []byte
+	// UIDValidity is the UIDVALIDITY of the mailbox, the message UID is only unique within it
+	UIDValidity uint32
+	// Attachments are the parts of the message that are meant to be saved as files, e.g. PDF statements
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
 }
 
 type Filter func(message Message) bool
@@ -0,0 +1,22 @@
+package enricher
+
+import (
+	"github.com/Philanthropists/toshl-email-autosync/internal/enricher/mercadolibre"
+	"github.com/Philanthropists/toshl-email-autosync/internal/enricher/rappi"
+	"github.com/Philanthropists/toshl-email-autosync/internal/enricher/uber"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+var enrichers []types.Enricher
+
+func init() {
+	enrichers = []types.Enricher{
+		rappi.Rappi{},
+		uber.Uber{},
+		mercadolibre.MercadoLibre{},
+	}
+}
+
+func GetEnrichers() []types.Enricher {
+	return enrichers
+}
@@ -0,0 +1,93 @@
+// Package enrichertest runs receipt enrichers against the text fixtures in their testdata directory
+package enrichertest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	_imap "github.com/emersion/go-imap"
+)
+
+// EnvelopeDate is the date of every fixture message, which is the date of the receipts
+var EnvelopeDate = time.Date(2022, 3, 13, 2, 20, 0, 0, time.UTC)
+
+// Receipt is the receipt expected from a fixture of the testdata directory
+type Receipt struct {
+	Fixture string
+	Total   string
+	Details string
+}
+
+// Place is a place of a bank alert and whether the enricher should match it
+type Place struct {
+	Place   string
+	Matches bool
+}
+
+// LoadMessage builds a message sent by the sender address, with the fixture of the testdata directory as body
+func LoadMessage(t *testing.T, sender, fixture string) imaptypes.Message {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("could not read fixture [%s]: %s", fixture, err)
+	}
+
+	at := strings.LastIndex(sender, "@")
+	from := &_imap.Address{MailboxName: sender[:at], HostName: sender[at+1:]}
+
+	return imaptypes.Message{
+		Message: &_imap.Message{
+			SeqNum: 1,
+			Envelope: &_imap.Envelope{
+				Date: EnvelopeDate,
+				From: []*_imap.Address{from},
+			},
+		},
+		RawBody: body,
+	}
+}
+
+// RunReceipts checks that the enricher keeps every fixture and extracts the expected receipt
+func RunReceipts(t *testing.T, enricher synctypes.Enricher, sender string, receipts []Receipt) {
+	for _, expected := range receipts {
+		t.Run(expected.Fixture, func(t *testing.T) {
+			msg := LoadMessage(t, sender, expected.Fixture)
+
+			if !enricher.FilterMessage(msg) {
+				t.Fatalf("message should not be filtered out")
+			}
+
+			receipt, err := enricher.ExtractReceiptFromMessage(msg)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if receipt.Total.Decimal() != expected.Total {
+				t.Errorf("total: got [%s], expected [%s]", receipt.Total.Decimal(), expected.Total)
+			}
+			if receipt.Details != expected.Details {
+				t.Errorf("details: got [%s], expected [%s]", receipt.Details, expected.Details)
+			}
+			if !receipt.Date.Equal(EnvelopeDate) {
+				t.Errorf("date: got [%s], expected [%s]", receipt.Date, EnvelopeDate)
+			}
+		})
+	}
+}
+
+// RunPlaces checks which places of bank alerts the enricher matches
+func RunPlaces(t *testing.T, enricher synctypes.Enricher, places []Place) {
+	for _, expected := range places {
+		t.Run(expected.Place, func(t *testing.T) {
+			if got := enricher.MatchesPlace(expected.Place); got != expected.Matches {
+				t.Errorf("got match %t, expected %t", got, expected.Matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package mercadolibre
+
+import (
+	"regexp"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+type MercadoLibre struct {
+}
+
+var itemRegexp = regexp.MustCompile(`(?im)^\s*compraste (?P<item>.+?)\s*$`)
+
+var totalRegexp = regexp.MustCompile(`(?i)pagaste\s*\$\s?(?P<value>[0-9.,]+)`)
+
+// placeRegexp matches the names Mercado Libre purchases have in bank alerts, as whole words since "MELI" is
+// also part of other names, e.g. "CAMELIA"
+var placeRegexp = regexp.MustCompile(`(?i)\b(?:MERCADO ?LIBRE|MERCADO ?PAGO|MELI)\b`)
+
+func (m MercadoLibre) Name() string {
+	return "Mercado Libre"
+}
+
+func (m MercadoLibre) FilterMessage(msg imaptypes.Message) bool {
+	return (common.IsFromDomain(msg, "mercadolibre.com.co") || common.IsFromDomain(msg, "mercadolibre.com")) &&
+		itemRegexp.Match(msg.RawBody)
+}
+
+func (m MercadoLibre) MatchesPlace(place string) bool {
+	return placeRegexp.MatchString(place)
+}
+
+func (m MercadoLibre) ExtractReceiptFromMessage(msg imaptypes.Message) (*synctypes.Receipt, error) {
+	text := string(msg.RawBody)
+
+	item := common.ExtractFieldsStringWithRegexp(text, itemRegexp)["item"]
+	if item == "" {
+		return nil, synctypes.NewMissingFieldError(m.Name(), "item")
+	}
+
+	total, err := common.GetValueFromText(common.ExtractFieldsStringWithRegexp(text, totalRegexp)["value"], "")
+	if err != nil {
+		return nil, synctypes.NewBadAmountError(m.Name(), err)
+	}
+
+	return &synctypes.Receipt{
+		Merchant: m.Name(),
+		Total:    total,
+		Date:     msg.Envelope.Date,
+		Details:  item,
+	}, nil
+}
@@ -0,0 +1,23 @@
+package mercadolibre
+
+import (
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/enricher/enrichertest"
+)
+
+func TestExtractReceiptFromMessage(t *testing.T) {
+	enrichertest.RunReceipts(t, MercadoLibre{}, "info@mercadolibre.com.co", []enrichertest.Receipt{
+		{Fixture: "compra.txt", Total: "89900.00", Details: "Audífonos Inalámbricos XYZ"},
+	})
+}
+
+func TestMatchesPlace(t *testing.T) {
+	enrichertest.RunPlaces(t, MercadoLibre{}, []enrichertest.Place{
+		{Place: "MERCADOPAGO COLOMBIA", Matches: true},
+		{Place: "DLO*MERCADO LIBRE", Matches: true},
+		{Place: "MELI*COMPRA", Matches: true},
+		{Place: "FLORISTERIA CAMELIA", Matches: false},
+		{Place: "RAPPI COLOMBIA*DL", Matches: false},
+	})
+}
@@ -0,0 +1,66 @@
+package rappi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+type Rappi struct {
+}
+
+var itemRegexp = regexp.MustCompile(`(?m)^\s*(?P<quantity>\d+)\s*x\s+(?P<item>.+?)\s+\$\s?[0-9.,]+\s*$`)
+
+var totalRegexp = regexp.MustCompile(`(?im)^\s*total(?: pagado)?:?\s*\$\s?(?P<value>[0-9.,]+)`)
+
+func (r Rappi) Name() string {
+	return "Rappi"
+}
+
+func (r Rappi) FilterMessage(msg imaptypes.Message) bool {
+	return (common.IsFromDomain(msg, "rappi.com") || common.IsFromDomain(msg, "rappi.com.co")) &&
+		common.OrderStoreRegexp.Match(msg.RawBody)
+}
+
+func (r Rappi) MatchesPlace(place string) bool {
+	return strings.Contains(strings.ToUpper(place), "RAPPI")
+}
+
+func (r Rappi) ExtractReceiptFromMessage(msg imaptypes.Message) (*synctypes.Receipt, error) {
+	text := string(msg.RawBody)
+
+	store := common.ExtractFieldsStringWithRegexp(text, common.OrderStoreRegexp)["store"]
+	if store == "" {
+		return nil, synctypes.NewMissingFieldError(r.Name(), "store")
+	}
+
+	total, err := common.GetValueFromText(common.ExtractFieldsStringWithRegexp(text, totalRegexp)["value"], "")
+	if err != nil {
+		return nil, synctypes.NewBadAmountError(r.Name(), err)
+	}
+
+	var items []string
+	for _, match := range itemRegexp.FindAllStringSubmatch(text, -1) {
+		quantity, item := match[itemRegexp.SubexpIndex("quantity")], match[itemRegexp.SubexpIndex("item")]
+		if quantity != "1" {
+			item = fmt.Sprintf("%s x %s", quantity, item)
+		}
+		items = append(items, item)
+	}
+
+	details := store
+	if len(items) > 0 {
+		details += ": " + strings.Join(items, ", ")
+	}
+
+	return &synctypes.Receipt{
+		Merchant: r.Name(),
+		Total:    total,
+		Date:     msg.Envelope.Date,
+		Details:  details,
+	}, nil
+}
@@ -0,0 +1,20 @@
+package rappi
+
+import (
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/enricher/enrichertest"
+)
+
+func TestExtractReceiptFromMessage(t *testing.T) {
+	enrichertest.RunReceipts(t, Rappi{}, "noreply@rappi.com", []enrichertest.Receipt{
+		{Fixture: "pedido.txt", Total: "43900.00", Details: "El Corral: Hamburguesa Corral Queso, 2 x Papas a la francesa"},
+	})
+}
+
+func TestMatchesPlace(t *testing.T) {
+	enrichertest.RunPlaces(t, Rappi{}, []enrichertest.Place{
+		{Place: "RAPPI COLOMBIA*DL", Matches: true},
+		{Place: "EXITO CALLE 80", Matches: false},
+	})
+}
@@ -0,0 +1,67 @@
+package uber
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+type Uber struct {
+}
+
+var totalRegexp = regexp.MustCompile(`(?im)^\s*total:?\s*\$\s?(?P<value>[0-9.,]+)`)
+
+// stopRegexp matches the stops of a trip, which are written after the time, e.g. "8:05 p. m. | Calle 80 #20-30"
+var stopRegexp = regexp.MustCompile(`(?m)^\s*\d{1,2}:\d{2}[^|\n]*\|\s*(?P<address>.+?)\s*$`)
+
+var productRegexp = regexp.MustCompile(`(?im)^\s*viaje (?P<product>.+?)\s*$`)
+
+func (u Uber) Name() string {
+	return "Uber"
+}
+
+func (u Uber) FilterMessage(msg imaptypes.Message) bool {
+	return common.IsFromDomain(msg, "uber.com") && totalRegexp.Match(msg.RawBody)
+}
+
+func (u Uber) MatchesPlace(place string) bool {
+	return strings.Contains(strings.ToUpper(place), "UBER")
+}
+
+func (u Uber) ExtractReceiptFromMessage(msg imaptypes.Message) (*synctypes.Receipt, error) {
+	text := string(msg.RawBody)
+
+	total, err := common.GetValueFromText(common.ExtractFieldsStringWithRegexp(text, totalRegexp)["value"], "")
+	if err != nil {
+		return nil, synctypes.NewBadAmountError(u.Name(), err)
+	}
+
+	var details string
+	if store := common.ExtractFieldsStringWithRegexp(text, common.OrderStoreRegexp)["store"]; store != "" {
+		details = "Uber Eats: " + store
+	} else {
+		stops := stopRegexp.FindAllStringSubmatch(text, -1)
+		if len(stops) < 2 {
+			return nil, synctypes.NewMissingFieldError(u.Name(), "route")
+		}
+
+		product := common.ExtractFieldsStringWithRegexp(text, productRegexp)["product"]
+		if product == "" {
+			product = "Viaje"
+		}
+
+		index := stopRegexp.SubexpIndex("address")
+		details = fmt.Sprintf("%s de %s a %s", product, stops[0][index], stops[len(stops)-1][index])
+	}
+
+	return &synctypes.Receipt{
+		Merchant: u.Name(),
+		Total:    total,
+		Date:     msg.Envelope.Date,
+		Details:  details,
+	}, nil
+}
@@ -0,0 +1,21 @@
+package uber
+
+import (
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/enricher/enrichertest"
+)
+
+func TestExtractReceiptFromMessage(t *testing.T) {
+	enrichertest.RunReceipts(t, Uber{}, "noreply@uber.com", []enrichertest.Receipt{
+		{Fixture: "viaje.txt", Total: "18500.00", Details: "UberX de Calle 80 #20-30, Bogotá a Carrera 7 #72-41, Bogotá"},
+		{Fixture: "eats.txt", Total: "33400.00", Details: "Uber Eats: Crepes & Waffles"},
+	})
+}
+
+func TestMatchesPlace(t *testing.T) {
+	enrichertest.RunPlaces(t, Uber{}, []enrichertest.Place{
+		{Place: "DLO*UBER RIDES", Matches: true},
+		{Place: "RAPPI COLOMBIA*DL", Matches: false},
+	})
+}
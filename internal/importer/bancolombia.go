@@ -0,0 +1,164 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/bancolombia"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+// MovementType is the transaction type of imported rows, since the file does not tell purchases from payments
+const MovementType = "Movimiento"
+
+var movementDateLayouts = []string{"2006/01/02", "02/01/2006", "2006-01-02", "20060102"}
+
+// plainNumberRegexp matches the numbers written by spreadsheets, without thousands separators. A dot followed
+// by three digits is a thousands separator (e.g. 13.900), so only one or two decimals are taken as plain
+var plainNumberRegexp = regexp.MustCompile(`^-?\d+(?:\.\d{1,2})?$`)
+
+type movementColumns struct {
+	date        int
+	description int
+	value       int
+}
+
+var accentReplacer = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u")
+
+func normalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = accentReplacer.Replace(s)
+	return strings.TrimSuffix(s, ".")
+}
+
+// findMovementColumns looks for the header row, files usually start with some rows about the account
+func findMovementColumns(rows [][]string) (int, movementColumns, bool) {
+	for i, row := range rows {
+		columns := movementColumns{date: -1, description: -1, value: -1}
+		for j, cell := range row {
+			switch normalizeHeader(cell) {
+			case "fecha":
+				columns.date = j
+			case "descripcion":
+				columns.description = j
+			case "valor":
+				columns.value = j
+			}
+		}
+
+		if columns.date >= 0 && columns.description >= 0 && columns.value >= 0 {
+			return i, columns, true
+		}
+	}
+
+	return 0, movementColumns{}, false
+}
+
+func parseMovementDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range movementDateLayouts {
+		if date, err := time.ParseInLocation(layout, s, common.GetLocalLocation()); err == nil {
+			return date, nil
+		}
+	}
+
+	// spreadsheets store dates as the number of days since 1899-12-30
+	if days, err := strconv.ParseFloat(s, 64); err == nil && days > 1 && days < 100000 {
+		epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, common.GetLocalLocation())
+		return epoch.AddDate(0, 0, int(days)), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date [%s]", s)
+}
+
+func parseMovementValue(s string) (types.Money, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "$")
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimSpace(strings.TrimPrefix(s, "-"))
+
+	var value types.Money
+	var err error
+	if plainNumberRegexp.MatchString(s) {
+		value, err = types.ParseMoney(types.DefaultCurrencyCode, s)
+	}
+	if !plainNumberRegexp.MatchString(s) || err != nil {
+		value, err = common.GetValueFromText(s, types.DefaultCurrencyCode)
+	}
+	if err != nil {
+		return types.Money{}, err
+	}
+
+	if negative {
+		value = value.Neg()
+	}
+
+	return value, nil
+}
+
+// ParseBancolombiaMovements builds the transactions of the movements file exported from the Bancolombia portal,
+// the file does not say which account it belongs to so it must be given
+func ParseBancolombiaMovements(rows [][]string, account string) ([]*types.TransactionInfo, error) {
+	header, columns, ok := findMovementColumns(rows)
+	if !ok {
+		return nil, fmt.Errorf("header row with FECHA, DESCRIPCIÓN and VALOR columns not found")
+	}
+
+	var transactions []*types.TransactionInfo
+	for i, row := range rows[header+1:] {
+		line := header + i + 2
+
+		if isEmptyRow(row) {
+			continue
+		}
+		if len(row) <= columns.date || len(row) <= columns.description || len(row) <= columns.value {
+			return nil, fmt.Errorf("row %d: missing columns", line)
+		}
+
+		date, err := parseMovementDate(row[columns.date])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", line, err)
+		}
+
+		value, err := parseMovementValue(row[columns.value])
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid value: %w", line, err)
+		}
+		if value.IsZero() {
+			continue
+		}
+
+		direction := types.Credit
+		if value.Units < 0 {
+			direction = types.Debit
+		}
+
+		transactions = append(transactions, &types.TransactionInfo{
+			Bank:       bancolombia.Bancolombia{},
+			Type:       MovementType,
+			Place:      strings.TrimSpace(row[columns.description]),
+			Value:      value.Abs(),
+			Account:    account,
+			Date:       date,
+			DateSource: types.DateSourceImport,
+			Direction:  direction,
+		})
+	}
+
+	return transactions, nil
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
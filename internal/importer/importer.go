@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadFile returns the rows of a CSV or XLSX file, the format is chosen by the file extension
+func ReadFile(name string) ([][]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return readCSV(f)
+	case ".xlsx":
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return readXLSX(f, info.Size())
+	default:
+		return nil, fmt.Errorf("unsupported file [%s], it must be a .csv or .xlsx file", name)
+	}
+}
+
+// utf8BOM is written by spreadsheet programs at the start of CSV files, it must not end up in the first cell
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// readCSV reads comma or semicolon separated files, the separator is the one that appears the most in the
+// first line
+func readCSV(r io.Reader) ([][]string, error) {
+	br := bufio.NewReader(r)
+
+	if bom, _ := br.Peek(len(utf8BOM)); bytes.Equal(bom, utf8BOM) {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+
+	const maxHeaderLength = 1024
+	firstLine, err := br.Peek(maxHeaderLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if i := bytes.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	return reader.ReadAll()
+}
@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+const movementsCSV = "\xef\xbb\xbfCuenta de Ahorros;*1234\n" +
+	"\n" +
+	"FECHA;DESCRIPCIÓN;SUCURSAL;DCTO.;VALOR;SALDO\n" +
+	"2022/03/12;COMPRA EN RAPPI COLOMBIA;;;-13.900,00;986.100,00\n" +
+	"2022/03/14;PAGO DE NOMINA EMPRESA SAS;;;2.500.000,00;3.486.100,00\n" +
+	";;;;;\n"
+
+func checkMovements(t *testing.T, transactions []*types.TransactionInfo) {
+	t.Helper()
+
+	expected := []struct {
+		place     string
+		value     string
+		date      string
+		direction types.Direction
+	}{
+		{"COMPRA EN RAPPI COLOMBIA", "13900.00", "2022-03-12", types.Debit},
+		{"PAGO DE NOMINA EMPRESA SAS", "2500000.00", "2022-03-14", types.Credit},
+	}
+
+	if len(transactions) != len(expected) {
+		t.Fatalf("got %d transactions, expected %d", len(transactions), len(expected))
+	}
+
+	for i, e := range expected {
+		tx := transactions[i]
+		got := []string{tx.Place, tx.Value.Decimal(), tx.Date.Format("2006-01-02"), tx.Direction.String(), tx.Account}
+		want := []string{e.place, e.value, e.date, e.direction.String(), "1234"}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("transaction %d is %v, expected %v", i, got, want)
+		}
+	}
+}
+
+func TestParseMovementValue(t *testing.T) {
+	var tests = []struct {
+		value    string
+		expected string
+	}{
+		{value: "-13900", expected: "-13900.00"},
+		{value: "2500000", expected: "2500000.00"},
+		{value: "13900.5", expected: "13900.50"},
+		{value: "-13.900", expected: "-13900.00"},
+		{value: "2.500.000", expected: "2500000.00"},
+		{value: "-13.900,00", expected: "-13900.00"},
+		{value: "$ 1.500", expected: "1500.00"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			value, err := parseMovementValue(test.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got := value.Decimal(); got != test.expected {
+				t.Errorf("got %s, expected %s", got, test.expected)
+			}
+		})
+	}
+}
+
+func TestParseBancolombiaMovementsCSV(t *testing.T) {
+	rows, err := readCSV(strings.NewReader(movementsCSV))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	transactions, err := ParseBancolombiaMovements(rows, "1234")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	checkMovements(t, transactions)
+}
+
+func TestParseBancolombiaMovementsXLSX(t *testing.T) {
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Movimientos" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Target="worksheets/movimientos.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst><si><t>FECHA</t></si><si><r><t>DESCRIP</t></r><r><t>CIÓN</t></r></si>` +
+			`<si><t>VALOR</t></si><si><t>COMPRA EN RAPPI COLOMBIA</t></si></sst>`,
+		"xl/worksheets/movimientos.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="s"><v>2</v></c></row>` +
+			`<row r="2"><c r="A2"><v>44632</v></c><c r="B2" t="s"><v>3</v></c><c r="D2"><v>-13900</v></c></row>` +
+			`<row r="3"><c r="A3" t="inlineStr"><is><t>2022/03/14</t></is></c>` +
+			`<c r="B3" t="inlineStr"><is><t>PAGO DE NOMINA EMPRESA SAS</t></is></c><c r="D3"><v>2500000</v></c></row>` +
+			`</sheetData></worksheet>`,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	rows, err := readXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	transactions, err := ParseBancolombiaMovements(rows, "1234")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	checkMovements(t, transactions)
+}
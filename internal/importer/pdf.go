@@ -0,0 +1,236 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/ledongthuc/pdf"
+)
+
+// statementMovementRegexp matches the rows of the movements table, the balance after the value is optional
+var statementMovementRegexp = regexp.MustCompile(`^(?P<date>\d{1,2}/\d{1,2}(?:/\d{4})?)\s+(?P<description>.+?)\s+(?P<value>-?\$?\s?[0-9][0-9.,]*)(?:\s+-?\$?\s?[0-9][0-9.,]*)?$`)
+
+// statementAccountRegexp matches the number of the account or card of the statement
+var statementAccountRegexp = regexp.MustCompile(`(?i)(?:cuenta|tarjeta)[^0-9\n]{0,30}(?P<account>[0-9][0-9\- ]{2,}[0-9])`)
+
+// statementDateRegexp matches the complete dates written in the statement, e.g. the period "2022/03/01 - 2022/03/31"
+var statementDateRegexp = regexp.MustCompile(`\b(?:\d{4}/\d{2}/\d{2}|\d{2}/\d{2}/\d{4})\b`)
+
+var multipleSpacesRegexp = regexp.MustCompile(`\s+`)
+
+// SignConvention is how the amounts of a statement tell debits from credits
+type SignConvention int
+
+const (
+	// DebitsNegative is the convention of savings accounts statements, withdrawals and purchases are negative
+	DebitsNegative SignConvention = iota
+	// DebitsPositive is the convention of credit card statements, purchases are positive and payments negative
+	DebitsPositive
+)
+
+// StatementSignConvention returns the sign convention of the statements of the account
+func StatementSignConvention(config types.PDFStatementsConfig, account string) SignConvention {
+	if config.IsCardAccount(account) {
+		return DebitsPositive
+	}
+	return DebitsNegative
+}
+
+// ReadPDF returns the lines of text of a PDF file, encrypted files are opened with the password
+func ReadPDF(r io.ReaderAt, size int64, password string) (lines []string, err error) {
+	// the PDF reader panics with some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			lines, err = nil, fmt.Errorf("could not read PDF: %v", r)
+		}
+	}()
+
+	tries := 0
+	reader, err := pdf.NewReaderEncrypted(r, size, func() string {
+		// the callback is called again while the password is wrong
+		tries++
+		if tries > 1 {
+			return ""
+		}
+		return password
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not open PDF: %w", err)
+	}
+
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		for _, row := range groupTextByRow(page.Content().Text) {
+			line := joinRowText(row)
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+// groupTextByRow groups the pieces of text by their vertical position, from the top of the page, and sorts every
+// row from left to right
+func groupTextByRow(texts []pdf.Text) [][]pdf.Text {
+	// pieces of the same row can be slightly misaligned, e.g. with different fonts
+	const rowTolerance = 2.0
+
+	sorted := make([]pdf.Text, len(texts))
+	copy(sorted, texts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Y > sorted[j].Y
+	})
+
+	var rows [][]pdf.Text
+	var rowY float64
+	for _, t := range sorted {
+		if len(rows) == 0 || rowY-t.Y > rowTolerance {
+			rows = append(rows, nil)
+			rowY = t.Y
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], t)
+	}
+
+	for _, row := range rows {
+		sort.SliceStable(row, func(i, j int) bool {
+			return row[i].X < row[j].X
+		})
+	}
+
+	return rows
+}
+
+// joinRowText joins the pieces of text of a row, pieces that are apart are separated by a space
+func joinRowText(texts []pdf.Text) string {
+	var sb strings.Builder
+	end := 0.0
+	for i, t := range texts {
+		width := t.W
+		if width == 0 {
+			width = t.FontSize * 0.5 * float64(len([]rune(t.S)))
+		}
+
+		if i > 0 && t.X > end+t.FontSize*0.2 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(t.S)
+		end = t.X + width
+	}
+
+	return strings.TrimSpace(multipleSpacesRegexp.ReplaceAllString(sb.String(), " "))
+}
+
+// ReadPDFFile returns the lines of text of the PDF file with the given name
+func ReadPDFFile(name string, password string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadPDF(f, info.Size(), password)
+}
+
+// StatementAccount returns the account number written in the statement
+func StatementAccount(lines []string) (string, bool) {
+	for _, line := range lines {
+		account := common.ExtractFieldsStringWithRegexp(line, statementAccountRegexp)["account"]
+		if account != "" {
+			return strings.NewReplacer("-", "", " ", "").Replace(account), true
+		}
+	}
+
+	return "", false
+}
+
+// statementPeriodEnd returns the latest complete date of the statement, movements without year belong to the
+// year that makes them end before it
+func statementPeriodEnd(lines []string) (time.Time, bool) {
+	var end time.Time
+	for _, line := range lines {
+		for _, s := range statementDateRegexp.FindAllString(line, -1) {
+			date, err := parseMovementDate(s)
+			if err == nil && date.After(end) {
+				end = date
+			}
+		}
+	}
+
+	return end, !end.IsZero()
+}
+
+// ParsePDFStatement builds the transactions of the movements table of a statement, every line that starts with a
+// date and ends with an amount is a movement. The sign of the amounts tells debits from credits according to the
+// convention of the statement
+func ParsePDFStatement(lines []string, account string, convention SignConvention) ([]*types.TransactionInfo, error) {
+	periodEnd, hasPeriod := statementPeriodEnd(lines)
+
+	var transactions []*types.TransactionInfo
+	for _, line := range lines {
+		result := common.ExtractFieldsStringWithRegexp(line, statementMovementRegexp)
+		if result["date"] == "" {
+			continue
+		}
+
+		date, err := parseMovementDate(result["date"])
+		if err != nil {
+			if !hasPeriod {
+				return nil, fmt.Errorf("movement [%s] has no year and the statement period was not found", line)
+			}
+
+			date, err = time.ParseInLocation("2/1/2006", fmt.Sprintf("%s/%d", result["date"], periodEnd.Year()), common.GetLocalLocation())
+			if err != nil {
+				continue
+			}
+			if date.After(periodEnd) {
+				date = date.AddDate(-1, 0, 0)
+			}
+		}
+
+		value, err := parseMovementValue(result["value"])
+		if err != nil || value.IsZero() {
+			continue
+		}
+
+		direction := types.Credit
+		if (value.Units < 0) == (convention == DebitsNegative) {
+			direction = types.Debit
+		}
+
+		transactions = append(transactions, &types.TransactionInfo{
+			Type:       MovementType,
+			Place:      result["description"],
+			Value:      value.Abs(),
+			Account:    account,
+			Date:       date,
+			DateSource: types.DateSourceImport,
+			Direction:  direction,
+		})
+	}
+
+	return transactions, nil
+}
+
+// IsPDF tells if the data is a PDF file
+func IsPDF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("%PDF-"))
+}
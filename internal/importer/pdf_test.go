@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+// newTestPDF writes a single page PDF with a line of text for every given line
+func newTestPDF(lines []string) []byte {
+	var content strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&content, "BT /F1 10 Tf 50 %d Td (%s) Tj ET\n", 750-i*15, line)
+	}
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+func TestParsePDFStatement(t *testing.T) {
+	data := newTestPDF([]string{
+		"EXTRACTO CUENTA DE AHORROS",
+		"Numero de cuenta: 123-456789-01",
+		"Desde: 2022/01/01 Hasta: 2022/01/31",
+		"FECHA DESCRIPCION VALOR SALDO",
+		"31/12 ABONO INTERESES AHORROS 1,25 100.001,25",
+		"12/01 COMPRA EN RAPPI COLOMBIA -13.900,00 86.101,25",
+		"14/01 PAGO DE NOMINA EMPRESA SAS 2.500.000,00 2.586.101,25",
+	})
+
+	lines, err := ReadPDF(bytes.NewReader(data), int64(len(data)), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	account, ok := StatementAccount(lines)
+	if !ok || account != "12345678901" {
+		t.Errorf("got account [%s], expected [12345678901]", account)
+	}
+
+	transactions, err := ParsePDFStatement(lines, account, DebitsNegative)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{
+		"2021-12-31|ABONO INTERESES AHORROS|1.25|credit",
+		"2022-01-12|COMPRA EN RAPPI COLOMBIA|13900.00|debit",
+		"2022-01-14|PAGO DE NOMINA EMPRESA SAS|2500000.00|credit",
+	}
+	if len(transactions) != len(expected) {
+		t.Fatalf("got %d transactions, expected %d: %v", len(transactions), len(expected), lines)
+	}
+
+	for i, e := range expected {
+		tx := transactions[i]
+		got := strings.Join([]string{tx.Date.Format("2006-01-02"), tx.Place, tx.Value.Decimal(), tx.Direction.String()}, "|")
+		if got != e {
+			t.Errorf("transaction %d is [%s], expected [%s]", i, got, e)
+		}
+	}
+}
+
+func TestParsePDFCardStatement(t *testing.T) {
+	data := newTestPDF([]string{
+		"EXTRACTO TARJETA DE CREDITO",
+		"Numero de tarjeta: 4567 1234 5678 1234",
+		"Desde: 2022/02/16 Hasta: 2022/03/15",
+		"FECHA DESCRIPCION VALOR",
+		"20/02 COMPRA EN FALABELLA UNICENTRO 89.000,00",
+		"01/03 PAGO SUCURSAL VIRTUAL -1.520.000,00",
+	})
+
+	lines, err := ReadPDF(bytes.NewReader(data), int64(len(data)), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	account, ok := StatementAccount(lines)
+	if !ok || account != "4567123456781234" {
+		t.Errorf("got account [%s], expected [4567123456781234]", account)
+	}
+
+	config := types.PDFStatementsConfig{CardAccounts: []string{"1234"}}
+	convention := StatementSignConvention(config, account)
+	if convention != DebitsPositive {
+		t.Fatalf("expected the card statement to have positive debits")
+	}
+
+	transactions, err := ParsePDFStatement(lines, account, convention)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{
+		"2022-02-20|COMPRA EN FALABELLA UNICENTRO|89000.00|debit",
+		"2022-03-01|PAGO SUCURSAL VIRTUAL|1520000.00|credit",
+	}
+	if len(transactions) != len(expected) {
+		t.Fatalf("got %d transactions, expected %d: %v", len(transactions), len(expected), lines)
+	}
+
+	for i, e := range expected {
+		tx := transactions[i]
+		got := strings.Join([]string{tx.Date.Format("2006-01-02"), tx.Place, tx.Value.Decimal(), tx.Direction.String()}, "|")
+		if got != e {
+			t.Errorf("transaction %d is [%s], expected [%s]", i, got, e)
+		}
+	}
+}
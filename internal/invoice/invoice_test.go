@@ -0,0 +1,93 @@
+package invoice
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+func loadTestInvoice(t *testing.T) *types.Invoice {
+	t.Helper()
+
+	content, err := os.ReadFile("testdata/attached-document.xml")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{"ad0890900608.xml": content, "fv0890900608.pdf": []byte("%PDF-1.4")} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		_, _ = w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	invoice, err := ParseZip(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return invoice
+}
+
+func TestParseZip(t *testing.T) {
+	invoice := loadTestInvoice(t)
+
+	if invoice.Number != "FE12345" || invoice.SupplierNIT != "890900608" || invoice.SupplierName != "ALMACENES EXITO S.A." {
+		t.Errorf("got invoice [%s %s %s]", invoice.Number, invoice.SupplierNIT, invoice.SupplierName)
+	}
+	if invoice.Total.String() != "COP 13,900.00" || invoice.IssueDate.Format("2006-01-02") != "2022-03-12" {
+		t.Errorf("got total [%s] issued on [%s]", invoice.Total, invoice.IssueDate)
+	}
+	if len(invoice.Lines) != 2 || invoice.Lines[0].Description != "LECHE ENTERA 1L" || invoice.Lines[0].Amount.Decimal() != "8403.36" {
+		t.Errorf("got lines %+v", invoice.Lines)
+	}
+	if len(invoice.Taxes) != 1 || invoice.Taxes[0].Name != "IVA" || invoice.Taxes[0].Value.Decimal() != "1596.64" {
+		t.Errorf("got taxes %+v", invoice.Taxes)
+	}
+
+	expected := "ALMACENES EXITO S.A. (NIT 890900608): 2 x LECHE ENTERA 1L, PAN TAJADO"
+	if summary := Summary(invoice); summary != expected {
+		t.Errorf("got summary [%s], expected [%s]", summary, expected)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	invoice := loadTestInvoice(t)
+
+	newTransaction := func(value string, direction types.Direction, day int) *types.TransactionInfo {
+		money, _ := types.ParseMoney("COP", value)
+		return &types.TransactionInfo{
+			Value:     money,
+			Direction: direction,
+			Date:      time.Date(2022, 3, day, 21, 14, 0, 0, common.GetLocalLocation()),
+		}
+	}
+
+	otherAmount := newTransaction("13000", types.Debit, 12)
+	income := newTransaction("13900", types.Credit, 12)
+	tooLate := newTransaction("13900", types.Debit, 20)
+	nearby := newTransaction("13900", types.Debit, 11)
+	closest := newTransaction("13900", types.Debit, 12)
+
+	Match([]*types.Invoice{invoice}, []*types.TransactionInfo{otherAmount, income, tooLate, nearby, closest})
+
+	if closest.Invoice != invoice {
+		t.Errorf("expected the invoice to match the closest purchase")
+	}
+	for _, tx := range []*types.TransactionInfo{otherAmount, income, tooLate, nearby} {
+		if tx.Invoice != nil {
+			t.Errorf("unexpected invoice for %+v", tx)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package invoice
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+// MaxDaysApart is how many days after or before the purchase the invoice can be issued
+const MaxDaysApart = 2
+
+// maxSummaryLines is how many line items are written in the summary of an invoice
+const maxSummaryLines = 5
+
+func day(t time.Time) time.Time {
+	t = t.In(common.GetLocalLocation())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func daysApart(a, b time.Time) int {
+	days := int(day(a).Sub(day(b)).Hours() / 24)
+	if days < 0 {
+		return -days
+	}
+	return days
+}
+
+// Match sets the invoice of the purchases that have the same amount and were made close to the issue date of an
+// invoice, every invoice is set to the closest purchase only. The invoices that match no purchase are returned
+func Match(invoices []*types.Invoice, transactions []*types.TransactionInfo) []*types.Invoice {
+	var unmatched []*types.Invoice
+	for _, invoice := range invoices {
+		var match *types.TransactionInfo
+		for _, t := range transactions {
+			if t.Invoice != nil || t.Direction != types.Debit || t.Value != invoice.Total {
+				continue
+			}
+
+			apart := daysApart(t.Date, invoice.IssueDate)
+			if apart > MaxDaysApart {
+				continue
+			}
+			if match == nil || apart < daysApart(match.Date, invoice.IssueDate) {
+				match = t
+			}
+		}
+
+		if match != nil {
+			match.Invoice = invoice
+		} else {
+			unmatched = append(unmatched, invoice)
+		}
+	}
+
+	return unmatched
+}
+
+// formatQuantity removes the trailing zeros of the decimals, invoices write quantities like "2.000000"
+func formatQuantity(quantity string) string {
+	if strings.Contains(quantity, ".") {
+		quantity = strings.TrimRight(strings.TrimRight(quantity, "0"), ".")
+	}
+
+	return quantity
+}
+
+// Summary describes the invoice with the legal name of the merchant and its line items
+func Summary(invoice *types.Invoice) string {
+	summary := invoice.SupplierName
+	if invoice.SupplierNIT != "" {
+		summary += fmt.Sprintf(" (NIT %s)", invoice.SupplierNIT)
+	}
+
+	var items []string
+	for i, line := range invoice.Lines {
+		if i == maxSummaryLines {
+			items = append(items, fmt.Sprintf("y %d más", len(invoice.Lines)-maxSummaryLines))
+			break
+		}
+
+		item := line.Description
+		if quantity := formatQuantity(line.Quantity); quantity != "" && quantity != "1" {
+			item = fmt.Sprintf("%s x %s", quantity, item)
+		}
+		items = append(items, item)
+	}
+
+	if len(items) > 0 {
+		summary += ": " + strings.Join(items, ", ")
+	}
+
+	return summary
+}
@@ -0,0 +1,200 @@
+package invoice
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+// ErrNoInvoice is returned when a file does not hold an electronic invoice
+var ErrNoInvoice = errors.New("no electronic invoice found")
+
+// only the UBL 2.1 elements needed are decoded, namespaces are ignored since the DIAN documents always use the
+// standard prefixes
+
+type ublAmount struct {
+	Value      string `xml:",chardata"`
+	CurrencyID string `xml:"currencyID,attr"`
+}
+
+type ublParty struct {
+	Names    []string `xml:"PartyName>Name"`
+	TaxNames []string `xml:"PartyTaxScheme>RegistrationName"`
+	TaxIDs   []string `xml:"PartyTaxScheme>CompanyID"`
+	Legal    []string `xml:"PartyLegalEntity>RegistrationName"`
+}
+
+type ublInvoice struct {
+	XMLName   xml.Name
+	ID        string   `xml:"ID"`
+	IssueDate string   `xml:"IssueDate"`
+	IssueTime string   `xml:"IssueTime"`
+	Supplier  ublParty `xml:"AccountingSupplierParty>Party"`
+	TaxTotals []struct {
+		Subtotals []struct {
+			Amount ublAmount `xml:"TaxAmount"`
+			Name   string    `xml:"TaxCategory>TaxScheme>Name"`
+		} `xml:"TaxSubtotal"`
+	} `xml:"TaxTotal"`
+	Total ublAmount `xml:"LegalMonetaryTotal>PayableAmount"`
+	Lines []struct {
+		Quantity    string    `xml:"InvoicedQuantity"`
+		Amount      ublAmount `xml:"LineExtensionAmount"`
+		Description []string  `xml:"Item>Description"`
+	} `xml:"InvoiceLine"`
+}
+
+// ublAttachedDocument is the container sent to buyers, it holds the invoice as text inside its attachment
+type ublAttachedDocument struct {
+	Descriptions []string `xml:"Attachment>ExternalReference>Description"`
+}
+
+func first(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func parseAmount(amount ublAmount) (types.Money, error) {
+	code := amount.CurrencyID
+	if code == "" {
+		code = types.DefaultCurrencyCode
+	}
+
+	return types.ParseMoneyRounded(code, strings.TrimSpace(amount.Value))
+}
+
+// ParseXML parses an UBL invoice, or the attached document that holds it
+func ParseXML(data []byte) (*types.Invoice, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid invoice XML: %w", err)
+	}
+
+	switch root.XMLName.Local {
+	case "Invoice":
+		return parseInvoice(data)
+	case "AttachedDocument":
+		var attached ublAttachedDocument
+		if err := xml.Unmarshal(data, &attached); err != nil {
+			return nil, fmt.Errorf("invalid attached document: %w", err)
+		}
+
+		for _, description := range attached.Descriptions {
+			if strings.Contains(description, "<Invoice") {
+				return parseInvoice([]byte(strings.TrimSpace(description)))
+			}
+		}
+	}
+
+	return nil, ErrNoInvoice
+}
+
+func parseInvoice(data []byte) (*types.Invoice, error) {
+	var ubl ublInvoice
+	if err := xml.Unmarshal(data, &ubl); err != nil {
+		return nil, fmt.Errorf("invalid invoice XML: %w", err)
+	}
+
+	total, err := parseAmount(ubl.Total)
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice total: %w", err)
+	}
+
+	issueDate, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(ubl.IssueDate), common.GetLocalLocation())
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice issue date: %w", err)
+	}
+
+	name := first(ubl.Supplier.TaxNames)
+	if name == "" {
+		name = first(ubl.Supplier.Legal)
+	}
+	if name == "" {
+		name = first(ubl.Supplier.Names)
+	}
+
+	invoice := &types.Invoice{
+		Number:       strings.TrimSpace(ubl.ID),
+		IssueDate:    issueDate,
+		SupplierNIT:  first(ubl.Supplier.TaxIDs),
+		SupplierName: name,
+		Total:        total,
+	}
+
+	for _, line := range ubl.Lines {
+		amount, err := parseAmount(line.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid invoice line amount: %w", err)
+		}
+
+		invoice.Lines = append(invoice.Lines, types.InvoiceLine{
+			Description: first(line.Description),
+			Quantity:    strings.TrimSpace(line.Quantity),
+			Amount:      amount,
+		})
+	}
+
+	for _, taxTotal := range ubl.TaxTotals {
+		for _, subtotal := range taxTotal.Subtotals {
+			amount, err := parseAmount(subtotal.Amount)
+			if err != nil {
+				return nil, fmt.Errorf("invalid invoice tax amount: %w", err)
+			}
+
+			invoice.Taxes = append(invoice.Taxes, types.Tax{
+				Name:  strings.TrimSpace(subtotal.Name),
+				Value: amount,
+			})
+		}
+	}
+
+	return invoice, nil
+}
+
+// ParseZip parses the invoice of the first XML file of a ZIP file, which is how invoices are emailed
+func ParseZip(data []byte) (*types.Invoice, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid invoice ZIP: %w", err)
+	}
+
+	for _, f := range zr.File {
+		if !strings.EqualFold(filepath.Ext(f.Name), ".xml") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		invoice, err := ParseXML(content)
+		if errors.Is(err, ErrNoInvoice) {
+			continue
+		}
+		return invoice, err
+	}
+
+	return nil, ErrNoInvoice
+}
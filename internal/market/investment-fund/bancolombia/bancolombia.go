@@ -9,7 +9,7 @@ import (
 	"strings"
 	"time"
 
-	"golang.org/x/text/currency"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
 )
 
 const (
@@ -19,32 +19,23 @@ const (
 	copUnitCode   = "COP"
 )
 
-var copUnit currency.Unit
-
-func init() {
-	var err error
-	copUnit, err = currency.ParseISO(copUnitCode)
-	if err != nil {
-		panic(err)
-	}
-}
-
-type money currency.Amount
+type money types.Money
 
 func (v *money) UnmarshalJSON(b []byte) error {
 	cleaned := strings.Trim(string(b), "\"$")
 	cleaned = strings.ReplaceAll(cleaned, ",", "")
-	value, err := strconv.ParseFloat(cleaned, 64)
+	// unit values can have more decimals than pesos do, they are only informative so rounding them is fine
+	value, err := types.ParseMoneyRounded(copUnitCode, cleaned)
 	if err != nil {
 		return err
 	}
 
-	*v = money(copUnit.Amount(value))
+	*v = money(value)
 	return nil
 }
 
 func (v money) String() string {
-	return fmt.Sprintf("%s", currency.Amount(v))
+	return types.Money(v).String()
 }
 
 type percentage float64
@@ -0,0 +1,160 @@
+package merchant
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+)
+
+//go:embed aliases.yaml
+var builtinAliases []byte
+
+// Alias maps the descriptors written by the banks to the canonical name of a merchant, a descriptor
+// matches when it starts with any of the prefixes or matches any of the regexps. Both are compared with
+// the descriptor upper cased and with its spaces collapsed
+type Alias struct {
+	Name     string   `json:"name" yaml:"name"`
+	Prefixes []string `json:"prefixes,omitempty" yaml:"prefixes,omitempty"`
+	Regexps  []string `json:"regexps,omitempty" yaml:"regexps,omitempty"`
+
+	prefixes []string
+	regexps  []*regexp.Regexp
+}
+
+type aliasFile struct {
+	Aliases []Alias `json:"aliases" yaml:"aliases"`
+}
+
+// Table normalizes merchant descriptors, the first alias that matches wins
+type Table struct {
+	aliases []Alias
+}
+
+func (a *Alias) compile() error {
+	if a.Name == "" {
+		return errors.New("alias name cannot be empty")
+	}
+	if len(a.Prefixes) == 0 && len(a.Regexps) == 0 {
+		return fmt.Errorf("alias [%s] needs at least one prefix or regexp", a.Name)
+	}
+
+	a.prefixes = nil
+	for _, prefix := range a.Prefixes {
+		a.prefixes = append(a.prefixes, normalizeDescriptor(prefix))
+	}
+
+	a.regexps = nil
+	for _, expr := range a.Regexps {
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("alias [%s] has an invalid regexp: %w", a.Name, err)
+		}
+		a.regexps = append(a.regexps, compiled)
+	}
+
+	return nil
+}
+
+func (a *Alias) matches(descriptor string) bool {
+	normalized := normalizeDescriptor(descriptor)
+	for _, prefix := range a.prefixes {
+		if strings.HasPrefix(normalized, prefix) {
+			return true
+		}
+	}
+
+	for _, r := range a.regexps {
+		if r.MatchString(normalized) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeDescriptor upper cases the descriptor and collapses its spaces, so prefixes do not depend on how
+// each bank writes it
+func normalizeDescriptor(descriptor string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(descriptor)), " ")
+}
+
+// NewTable validates the aliases and compiles their rules
+func NewTable(aliases []Alias) (*Table, error) {
+	table := &Table{}
+
+	var problems []string
+	for _, alias := range aliases {
+		if err := alias.compile(); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		table.aliases = append(table.aliases, alias)
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "\n"))
+	}
+
+	return table, nil
+}
+
+// Normalize returns the canonical name of the merchant of the descriptor, or false when no alias matches
+func (t *Table) Normalize(descriptor string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+
+	for i := range t.aliases {
+		if t.aliases[i].matches(descriptor) {
+			return t.aliases[i].Name, true
+		}
+	}
+
+	return "", false
+}
+
+// BuiltinAliases returns the aliases shipped with the application
+func BuiltinAliases() []Alias {
+	var file aliasFile
+	if err := common.DecodeConfig("aliases.yaml", builtinAliases, &file); err != nil {
+		panic(err)
+	}
+
+	return file.Aliases
+}
+
+// LoadFile reads the aliases of a user file
+func LoadFile(path string) ([]Alias, error) {
+	var file aliasFile
+	if err := common.DecodeConfigFile(path, &file); err != nil {
+		return nil, fmt.Errorf("could not load merchant aliases file [%s]: %w", path, err)
+	}
+
+	return file.Aliases, nil
+}
+
+// Load builds a table with the built-in aliases, preceded by the aliases of the user file when path is
+// not empty so they can override the built-in ones
+func Load(path string) (*Table, error) {
+	var aliases []Alias
+	if path != "" {
+		userAliases, err := LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		aliases = append(aliases, userAliases...)
+	}
+
+	aliases = append(aliases, BuiltinAliases()...)
+
+	table, err := NewTable(aliases)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merchant aliases: %w", err)
+	}
+
+	return table, nil
+}
@@ -0,0 +1,63 @@
+package merchant
+
+import "testing"
+
+func TestBuiltinAliases(t *testing.T) {
+	table, err := Load("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]string{
+		"PAYU*NETFLIX BOGOTA":    "Netflix",
+		"DLO*SPOTIFY":            "Spotify",
+		"UBER   EATS BOGOTA":     "Uber Eats",
+		"UBER *TRIP":             "Uber",
+		"RAPPI COLOMBIA*DL":      "Rappi",
+		"almacenes exito colin":  "Éxito",
+		"SAO 43 BARRANQUILLA":    "Olímpica",
+		"TIENDA D1 CHAPINERO":    "D1",
+		"GOOGLE *YouTubePremium": "YouTube",
+	}
+	for descriptor, expected := range cases {
+		name, ok := table.Normalize(descriptor)
+		if !ok || name != expected {
+			t.Errorf("%s: expected [%s], got [%s]", descriptor, expected, name)
+		}
+	}
+
+	if name, ok := table.Normalize("PANADERIA LA 80"); ok {
+		t.Errorf("unexpected alias [%s]", name)
+	}
+}
+
+func TestUserAliasesOverrideBuiltin(t *testing.T) {
+	table, err := Load("testdata/aliases.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := map[string]string{
+		"PAYU*NETFLIX BOGOTA": "Netflix Familia",
+		"NETFLIX.COM":         "Netflix",
+		"bodytech andino":     "Gimnasio",
+	}
+	for descriptor, expected := range cases {
+		name, ok := table.Normalize(descriptor)
+		if !ok || name != expected {
+			t.Errorf("%s: expected [%s], got [%s]", descriptor, expected, name)
+		}
+	}
+}
+
+func TestInvalidAliases(t *testing.T) {
+	aliases := []Alias{
+		{Name: "", Prefixes: []string{"X"}},
+		{Name: "No rules"},
+		{Name: "Bad regexp", Regexps: []string{"("}},
+	}
+
+	if _, err := NewTable(aliases); err == nil {
+		t.Fatal("expected an error for invalid aliases")
+	}
+}
@@ -0,0 +1,209 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+// Match are the conditions of a rule, all the conditions that are set must hold for the rule to apply
+type Match struct {
+	// Merchant is a regexp matched against the merchant name and the place written by the bank
+	Merchant string `json:"merchant,omitempty" yaml:"merchant,omitempty"`
+	// MinAmount and MaxAmount bound the transaction value, both included, in Currency
+	MinAmount string `json:"min-amount,omitempty" yaml:"min-amount,omitempty"`
+	MaxAmount string `json:"max-amount,omitempty" yaml:"max-amount,omitempty"`
+	// Currency of the transaction, COP by default when there are amounts
+	Currency string `json:"currency,omitempty" yaml:"currency,omitempty"`
+	Bank     string `json:"bank,omitempty" yaml:"bank,omitempty"`
+	Account  string `json:"account,omitempty" yaml:"account,omitempty"`
+	// Type is the transaction type written by the bank, e.g. compra or transferencia
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Weekdays are written in english or spanish, e.g. saturday or sábado
+	Weekdays []string `json:"weekdays,omitempty" yaml:"weekdays,omitempty"`
+}
+
+// Rule assigns a Toshl category and tags to the transactions that match it
+type Rule struct {
+	Name     string   `json:"name" yaml:"name"`
+	Match    Match    `json:"match" yaml:"match"`
+	Category string   `json:"category" yaml:"category"`
+	Tags     []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	merchant  *regexp.Regexp
+	minAmount *types.Money
+	maxAmount *types.Money
+	weekdays  map[time.Weekday]bool
+}
+
+type rulesFile struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// RuleSet is an ordered list of rules, the first rule that matches a transaction wins
+type RuleSet struct {
+	rules []Rule
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+	"domingo":   time.Sunday,
+	"lunes":     time.Monday,
+	"martes":    time.Tuesday,
+	"miércoles": time.Wednesday,
+	"miercoles": time.Wednesday,
+	"jueves":    time.Thursday,
+	"viernes":   time.Friday,
+	"sábado":    time.Saturday,
+	"sabado":    time.Saturday,
+}
+
+func (r *Rule) compile() []string {
+	var problems []string
+
+	if r.Category == "" {
+		problems = append(problems, "category cannot be empty")
+	}
+
+	if r.Match.Merchant != "" {
+		compiled, err := regexp.Compile(r.Match.Merchant)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid merchant regexp: %s", err))
+		}
+		r.merchant = compiled
+	}
+
+	if r.Match.Currency == "" && (r.Match.MinAmount != "" || r.Match.MaxAmount != "") {
+		r.Match.Currency = types.DefaultCurrencyCode
+	}
+
+	parseAmount := func(name, amount string) *types.Money {
+		if amount == "" {
+			return nil
+		}
+
+		value, err := types.ParseMoney(r.Match.Currency, amount)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid %s: %s", name, err))
+			return nil
+		}
+		return &value
+	}
+	r.minAmount = parseAmount("min-amount", r.Match.MinAmount)
+	r.maxAmount = parseAmount("max-amount", r.Match.MaxAmount)
+
+	if r.minAmount != nil && r.maxAmount != nil && r.minAmount.Units > r.maxAmount.Units {
+		problems = append(problems, "min-amount cannot be greater than max-amount")
+	}
+
+	r.weekdays = nil
+	for _, name := range r.Match.Weekdays {
+		weekday, ok := weekdayNames[strings.ToLower(name)]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown weekday [%s]", name))
+			continue
+		}
+
+		if r.weekdays == nil {
+			r.weekdays = make(map[time.Weekday]bool)
+		}
+		r.weekdays[weekday] = true
+	}
+
+	return problems
+}
+
+// Matches tells if every condition of the rule holds for the transaction
+func (r *Rule) Matches(t *types.TransactionInfo) bool {
+	if r.merchant != nil && !r.merchant.MatchString(t.Place) && (t.Merchant == "" || !r.merchant.MatchString(t.Merchant)) {
+		return false
+	}
+
+	if r.Match.Currency != "" && r.Match.Currency != t.Value.Currency {
+		return false
+	}
+	if r.minAmount != nil && t.Value.Abs().Units < r.minAmount.Units {
+		return false
+	}
+	if r.maxAmount != nil && t.Value.Abs().Units > r.maxAmount.Units {
+		return false
+	}
+
+	if r.Match.Bank != "" && (t.Bank == nil || !strings.EqualFold(t.Bank.Name(), r.Match.Bank)) {
+		return false
+	}
+
+	if r.Match.Account != "" && t.Account != r.Match.Account {
+		return false
+	}
+
+	if r.Match.Type != "" && !strings.EqualFold(t.Type, r.Match.Type) {
+		return false
+	}
+
+	if r.weekdays != nil && !r.weekdays[t.Date.In(common.GetLocalLocation()).Weekday()] {
+		return false
+	}
+
+	return true
+}
+
+// NewRuleSet validates the rules and compiles their conditions
+func NewRuleSet(rules []Rule) (*RuleSet, error) {
+	ruleSet := &RuleSet{}
+
+	var problems []string
+	for i, rule := range rules {
+		for _, problem := range rule.compile() {
+			problems = append(problems, fmt.Sprintf("rule %d [%s]: %s", i+1, rule.Name, problem))
+		}
+		ruleSet.rules = append(ruleSet.rules, rule)
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "\n"))
+	}
+
+	return ruleSet, nil
+}
+
+// Match returns the first rule that matches the transaction
+func (s *RuleSet) Match(t *types.TransactionInfo) (*Rule, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	for i := range s.rules {
+		if s.rules[i].Matches(t) {
+			return &s.rules[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// LoadFile reads and validates the rules of the file
+func LoadFile(path string) (*RuleSet, error) {
+	var file rulesFile
+	if err := common.DecodeConfigFile(path, &file); err != nil {
+		return nil, fmt.Errorf("could not load rules file [%s]: %w", path, err)
+	}
+
+	ruleSet, err := NewRuleSet(file.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return ruleSet, nil
+}
@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/bancolombia"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+func TestRuleSetMatch(t *testing.T) {
+	ruleSet, err := LoadFile("testdata/rules.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	saturday := time.Date(2022, 3, 12, 10, 0, 0, 0, common.GetLocalLocation())
+	monday := time.Date(2022, 3, 14, 10, 0, 0, 0, common.GetLocalLocation())
+
+	newTransaction := func(place, merchant, value string, date time.Time) *types.TransactionInfo {
+		money, err := types.ParseMoney("COP", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return &types.TransactionInfo{
+			Bank:     bancolombia.Bancolombia{},
+			Type:     "Compra",
+			Place:    place,
+			Merchant: merchant,
+			Value:    money,
+			Account:  "1234",
+			Date:     date,
+		}
+	}
+
+	cases := []struct {
+		name     string
+		tx       *types.TransactionInfo
+		expected string
+	}{
+		{"merchant", newTransaction("PAYU*NETFLIX BOGOTA", "Netflix", "38900", monday), "Suscripciones"},
+		{"weekday", newTransaction("ALMACENES EXITO", "Éxito", "120000", saturday), "Mercado del fin de semana"},
+		{"amount", newTransaction("ALMACENES EXITO", "Éxito", "900000", monday), "Compras grandes con tarjeta"},
+		{"fallback rule", newTransaction("CARULLA 140", "Carulla", "120000", monday), "Mercado"},
+		{"no rule", newTransaction("PANADERIA LA 80", "", "5000", monday), ""},
+	}
+
+	for _, c := range cases {
+		rule, ok := ruleSet.Match(c.tx)
+		switch {
+		case c.expected == "" && ok:
+			t.Errorf("%s: unexpected rule [%s]", c.name, rule.Name)
+		case c.expected != "" && (!ok || rule.Name != c.expected):
+			t.Errorf("%s: expected rule [%s], got %+v", c.name, c.expected, rule)
+		}
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	rules := []Rule{
+		{Name: "no category"},
+		{Name: "bad regexp", Category: "X", Match: Match{Merchant: "("}},
+		{Name: "bad amount", Category: "X", Match: Match{MinAmount: "mil"}},
+		{Name: "bad range", Category: "X", Match: Match{MinAmount: "10", MaxAmount: "5"}},
+		{Name: "bad weekday", Category: "X", Match: Match{Weekdays: []string{"funday"}}},
+	}
+
+	for _, rule := range rules {
+		if _, err := NewRuleSet([]Rule{rule}); err == nil {
+			t.Errorf("%s: expected an error", rule.Name)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package sync
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/classifier"
+	"github.com/Philanthropists/toshl-email-autosync/internal/dynamodb"
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	defaultClassifierThreshold = 0.8
+	defaultHistoryMonths       = 24
+	defaultRetrainDays         = 7
+
+	// classifierItemId is the item of the toshl-data table that keeps the model, the processed date is item 1
+	classifierItemId = "2"
+)
+
+func classifierThreshold(config synctypes.ClassifierConfig) float64 {
+	if config.Threshold <= 0 {
+		return defaultClassifierThreshold
+	}
+	return config.Threshold
+}
+
+// trainingExamples are the entries categorized by hand, the ones still PENDING and the transfers are left out
+func trainingExamples(entries []*toshl.Entry, categories []toshl.Category) []classifier.Example {
+	const pendingCategory = "PENDING"
+
+	byId := make(map[string]toshl.Category, len(categories))
+	for _, c := range categories {
+		byId[c.ID] = c
+	}
+
+	var examples []classifier.Example
+	for _, entry := range entries {
+		category, ok := byId[entry.Category]
+		if !ok || category.Name == pendingCategory || entry.Description == nil {
+			continue
+		}
+
+		amount, err := entry.Money()
+		if err != nil {
+			continue
+		}
+
+		examples = append(examples, classifier.Example{
+			Type:        category.Type,
+			Description: *entry.Description,
+			Amount:      amount.Abs(),
+			Category:    category.Name,
+			Tags:        entry.Tags,
+		})
+	}
+
+	return examples
+}
+
+// TrainClassifier learns the categories and tags of the entries of the last months
+func TrainClassifier(toshlClient toshl.ApiClient, config synctypes.ClassifierConfig) (*classifier.Model, error) {
+	months := config.HistoryMonths
+	if months == 0 {
+		months = defaultHistoryMonths
+	}
+
+	to := time.Now()
+	entries, err := toshlClient.GetEntriesHistory(to.AddDate(0, -int(months), 0), to)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := toshlClient.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	return classifier.Train(trainingExamples(entries, categories)), nil
+}
+
+// LoadClassifier returns the persisted model, it is trained again and persisted when it is missing or too old
+func LoadClassifier(toshlClient toshl.ApiClient, config synctypes.ClassifierConfig) (*classifier.Model, error) {
+	log := logger.GetLogger()
+
+	retrainDays := config.RetrainDays
+	if retrainDays == 0 {
+		retrainDays = defaultRetrainDays
+	}
+
+	model, err := getStoredModel()
+	if err != nil {
+		log.Warnw("could not get the stored classifier model",
+			"error", err)
+	}
+	if model != nil && time.Since(model.TrainedAt) < time.Duration(retrainDays)*24*time.Hour {
+		return model, nil
+	}
+
+	model, err = TrainClassifier(toshlClient, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to train the classifier: %w", err)
+	}
+
+	if err := storeModel(model); err != nil {
+		log.Errorw("could not store the classifier model, it will be trained again on the next run",
+			"error", err)
+	}
+
+	return model, nil
+}
+
+// Classify sets the predicted category and tags of the transactions that no rule categorized
+func Classify(model *classifier.Model, transactions []*synctypes.TransactionInfo, threshold float64) {
+	log := logger.GetLogger()
+
+	categoryTypes := map[synctypes.Direction]string{
+		synctypes.Debit:  classifier.ExpenseType,
+		synctypes.Credit: classifier.IncomeType,
+	}
+
+	for _, t := range transactions {
+		if t.Category != "" {
+			continue
+		}
+
+		prediction, ok := model.Predict(categoryTypes[t.Direction], entryDescription(t), t.Value, threshold)
+		if !ok {
+			continue
+		}
+
+		log.Debugf("predicted category of %s: %s (%.2f)", t.Place, prediction.Category, prediction.Confidence)
+		t.Category = prediction.Category
+		t.TagIds = append(t.TagIds, prediction.Tags...)
+	}
+}
+
+func getStoredModel() (*classifier.Model, error) {
+	const idField = "Id"
+	const modelField = "Model"
+	const tableName = "toshl-data"
+
+	client, err := dynamodb.NewClient("us-east-1")
+	if err != nil {
+		return nil, err
+	}
+
+	key := map[string]dynamodb.AttributeValue{
+		idField: {
+			AttributeValue: &types.AttributeValueMemberN{Value: classifierItemId},
+		},
+	}
+
+	item, err := client.GetItem(tableName, key)
+	if err != nil {
+		return nil, err
+	}
+
+	compressed, ok := item[modelField].([]byte)
+	if !ok {
+		return nil, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var model classifier.Model
+	if err := json.Unmarshal(raw, &model); err != nil {
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// storeModel keeps the model compressed, since items cannot be larger than 400KB
+func storeModel(model *classifier.Model) error {
+	const idField = "Id"
+	const modelField = "Model"
+	const tableName = "toshl-data"
+
+	raw, err := json.Marshal(model)
+	if err != nil {
+		return err
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(raw); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	client, err := dynamodb.NewClient("us-east-1")
+	if err != nil {
+		return err
+	}
+
+	key := map[string]dynamodb.AttributeValue{
+		idField: {
+			AttributeValue: &types.AttributeValueMemberN{Value: classifierItemId},
+		},
+	}
+
+	expressionAttributeValues := map[string]dynamodb.AttributeValue{
+		":m": {
+			AttributeValue: &types.AttributeValueMemberB{Value: compressed.Bytes()},
+		},
+	}
+
+	updateExpression := fmt.Sprintf("set %s = :m", modelField)
+
+	return client.UpdateItem(tableName, key, expressionAttributeValues, updateExpression)
+}
@@ -0,0 +1,36 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+func TestTrainingExamples(t *testing.T) {
+	newCategory := func(id, name, categoryType string) toshl.Category {
+		var c toshl.Category
+		c.ID, c.Name, c.Type = id, name, categoryType
+		return c
+	}
+	categories := []toshl.Category{
+		newCategory("pending", "PENDING", expenseCategoryType),
+		newCategory("market", "Mercado", expenseCategoryType),
+	}
+
+	categorized := newTestEntry("categorized", "card", "2022-03-12", "** Compra de Éxito", -185000)
+	categorized.Category = "market"
+	categorized.Tags = []string{"home"}
+	pending := newTestEntry("pending", "card", "2022-03-12", "** Compra de Carulla", -50000)
+	pending.Category = "pending"
+	unknown := newTestEntry("transfer", "card", "2022-03-12", "** Transferencia", -50000)
+
+	examples := trainingExamples([]*toshl.Entry{categorized, pending, unknown}, categories)
+	if len(examples) != 1 {
+		t.Fatalf("expected only the categorized entry, got %+v", examples)
+	}
+
+	e := examples[0]
+	if e.Category != "Mercado" || e.Type != expenseCategoryType || e.Amount.Units != 18500000 || len(e.Tags) != 1 {
+		t.Errorf("unexpected example %+v", e)
+	}
+}
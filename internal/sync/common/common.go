@@ -2,7 +2,10 @@ package common
 
 import (
 	"regexp"
+	"strings"
+	"time"
 
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
 	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
 )
 
@@ -21,20 +24,21 @@ func ExtractFieldsStringWithRegexp(s string, r *regexp.Regexp) map[string]string
 }
 
 func ContainsAllRequiredFields(fields map[string]string) bool {
+	return len(MissingRequiredFields(fields)) == 0
+}
+
+// MissingRequiredFields returns the required fields that are not present or empty, in a stable order
+func MissingRequiredFields(fields map[string]string) []string {
 	requiredFields := []string{"value", "type", "place", "account"}
-	requiredFieldsSet := map[string]struct{}{}
-	for _, field := range requiredFields {
-		requiredFieldsSet[field] = struct{}{}
-	}
 
-	for field := range requiredFieldsSet {
-		_, ok := fields[field]
-		if !ok {
-			return false
+	var missing []string
+	for _, field := range requiredFields {
+		if value, ok := fields[field]; !ok || value == "" {
+			missing = append(missing, field)
 		}
 	}
 
-	return true
+	return missing
 }
 
 func PrintVersion(commit string) {
@@ -48,3 +52,50 @@ func PrintVersion(commit string) {
 func GetVersion() string {
 	return version
 }
+
+var nonDigitRegexp = regexp.MustCompile(`\D`)
+
+// NormalizePhoneNumber removes any formatting and the Colombian country code from a phone number,
+// so that it can be used as an account key in the same way as the last digits of a card
+func NormalizePhoneNumber(phone string) string {
+	digits := nonDigitRegexp.ReplaceAllString(phone, "")
+	if len(digits) == 12 && strings.HasPrefix(digits, "57") {
+		digits = digits[2:]
+	}
+
+	return digits
+}
+
+// OrderStoreRegexp matches the store of delivery orders, e.g. "Tu pedido de El Corral fue entregado"
+var OrderStoreRegexp = regexp.MustCompile(`(?i)tu pedido (?:de|en) (?P<store>.+?) (?:fue|ha sido|est[aá])\b`)
+
+// IsFromDomain tells if the message was sent from an address of the domain or of one of its subdomains
+func IsFromDomain(msg imaptypes.Message, domain string) bool {
+	if msg.Message == nil || msg.Message.Envelope == nil {
+		return false
+	}
+
+	for _, address := range msg.Message.Envelope.From {
+		host := strings.ToLower(address.HostName)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var localLocation *time.Location
+
+func init() {
+	var err error
+	localLocation, err = time.LoadLocation("America/Bogota")
+	if err != nil {
+		panic(err)
+	}
+}
+
+// GetLocalLocation returns the location in which banks express the dates inside their messages
+func GetLocalLocation() *time.Location {
+	return localLocation
+}
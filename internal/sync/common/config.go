@@ -0,0 +1,33 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeConfig decodes the data of a configuration file into v, as JSON or YAML according to the extension of path
+func DecodeConfig(path string, raw []byte, v interface{}) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return json.Unmarshal(raw, v)
+	case ".yaml", ".yml":
+		return yaml.Unmarshal(raw, v)
+	default:
+		return errors.New("unsupported file extension, expected .json, .yaml or .yml")
+	}
+}
+
+// DecodeConfigFile reads a .json, .yaml or .yml configuration file into v
+func DecodeConfigFile(path string, v interface{}) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return DecodeConfig(path, raw, v)
+}
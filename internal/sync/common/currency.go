@@ -0,0 +1,73 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+// This would be way easier if banks like Bancolombia had a consistent use of commas and dots inside the currency
+var currencyRegexp = regexp.MustCompile(`^(?P<integer>[0-9\.,]+)[\.,](?P<decimal>\d{2})$`)
+var currencyRegexpWithoutDecimal = regexp.MustCompile(`^(?P<integer>[0-9\.,]+)`)
+
+func getValueFromTextWithDecimal(s string) (string, error) {
+	if !currencyRegexp.MatchString(s) {
+		return "", fmt.Errorf("string [%s] does not match regex [%s]", s, currencyRegexp.String())
+	}
+
+	res := ExtractFieldsStringWithRegexp(s, currencyRegexp)
+	integer, ok := res["integer"]
+	if !ok {
+		return "", fmt.Errorf("string [%s] should have an integer part", s)
+	}
+
+	decimal, ok := res["decimal"]
+	if !ok {
+		return "", fmt.Errorf("string [%s] should have a decimal part", s)
+	}
+
+	integer = strings.ReplaceAll(integer, ",", "")
+	integer = strings.ReplaceAll(integer, ".", "")
+	valueStr := integer + "." + decimal
+
+	return valueStr, nil
+}
+
+func getValueFromTextWithoutDecimal(s string) (string, error) {
+	if !currencyRegexpWithoutDecimal.MatchString(s) {
+		return "", fmt.Errorf("string [%s] does not match regex without decimal [%s]", s, currencyRegexp.String())
+	}
+
+	res := ExtractFieldsStringWithRegexp(s, currencyRegexpWithoutDecimal)
+	integer, ok := res["integer"]
+	if !ok {
+		return "", fmt.Errorf("string [%s] should have an integer part", s)
+	}
+
+	integer = strings.ReplaceAll(integer, ",", "")
+	integer = strings.ReplaceAll(integer, ".", "")
+	decimal := "0"
+	valueStr := integer + "." + decimal
+
+	return valueStr, nil
+}
+
+// GetValueFromText parses the amount in s, code is the ISO 4217 currency of the amount and defaults to COP when empty
+func GetValueFromText(s string, code string) (synctypes.Money, error) {
+	valueStr, err := getValueFromTextWithDecimal(s)
+	if err != nil {
+		valueStr, err = getValueFromTextWithoutDecimal(s)
+	}
+
+	if err != nil {
+		return synctypes.Money{}, err
+	}
+
+	if code == "" {
+		code = synctypes.DefaultCurrencyCode
+	}
+
+	return synctypes.ParseMoney(code, valueStr)
+}
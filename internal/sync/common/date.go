@@ -0,0 +1,69 @@
+package common
+
+import (
+	"regexp"
+	"time"
+
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+const (
+	BodyDateLayout = "02/01/2006"
+	BodyTimeLayout = "15:04"
+)
+
+const (
+	// maxBodyDateAge is how long before the email a date in the body can be the transaction date, older dates
+	// are of something else, e.g. the due date or the period of a fee
+	maxBodyDateAge = 5 * 24 * time.Hour
+	// maxBodyDateSkew is how long after the email a date in the body can be, for clocks that are off
+	maxBodyDateSkew = time.Hour
+)
+
+// DefaultBodyDateRegexps match the most common ways banks write the transaction date, they are tried in order
+// and must have a "date" named group and optionally a "time" named group
+var DefaultBodyDateRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`(?P<date>\d{2}/\d{2}/\d{4}) a las (?P<time>\d{1,2}:\d{2})`),
+	regexp.MustCompile(`(?P<time>\d{2}:\d{2})\.? (?P<date>\d{2}/\d{2}/\d{4})`),
+	regexp.MustCompile(`(?P<date>\d{2}/\d{2}/\d{4}) (?P<time>\d{2}:\d{2})`),
+	regexp.MustCompile(`(?P<date>\d{2}/\d{2}/\d{4})`),
+}
+
+// GetTransactionDate returns the transaction date written in the text, or the envelope date when the text has none
+// or its date cannot be the date of the transaction
+func GetTransactionDate(text string, envelopeDate time.Time) (time.Time, synctypes.DateSource) {
+	return GetTransactionDateWithRegexps(text, DefaultBodyDateRegexps, envelopeDate)
+}
+
+func GetTransactionDateWithRegexps(text string, exps []*regexp.Regexp, envelopeDate time.Time) (time.Time, synctypes.DateSource) {
+	for _, exp := range exps {
+		result := ExtractFieldsStringWithRegexp(text, exp)
+		date, ok := result["date"]
+		if !ok || date == "" {
+			continue
+		}
+
+		value, layout := date, BodyDateLayout
+		if hour := result["time"]; hour != "" {
+			value, layout = date+" "+hour, BodyDateLayout+" "+BodyTimeLayout
+		}
+
+		parsed, err := time.ParseInLocation(layout, value, localLocation)
+		if err != nil || !plausibleBodyDate(parsed, envelopeDate) {
+			continue
+		}
+
+		return parsed, synctypes.DateSourceBody
+	}
+
+	return envelopeDate, synctypes.DateSourceEnvelope
+}
+
+// plausibleBodyDate tells if the date in the body can be the date of a transaction notified on the envelope date
+func plausibleBodyDate(date, envelopeDate time.Time) bool {
+	if envelopeDate.IsZero() {
+		return true
+	}
+
+	return !date.After(envelopeDate.Add(maxBodyDateSkew)) && !date.Before(envelopeDate.Add(-maxBodyDateAge))
+}
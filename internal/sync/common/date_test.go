@@ -0,0 +1,63 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+func TestGetTransactionDate(t *testing.T) {
+	envelopeDate := time.Date(2022, 3, 15, 8, 0, 0, 0, time.UTC)
+
+	var tests = []struct {
+		text     string
+		expected time.Time
+		source   synctypes.DateSource
+	}{
+		{
+			text:     "Enviaste $50.000 a la cuenta Nequi 310 987 6543 el 12/03/2022 a las 23:50.",
+			expected: time.Date(2022, 3, 12, 23, 50, 0, 0, localLocation),
+			source:   synctypes.DateSourceBody,
+		},
+		{
+			text:     "Compra por $13.900,00 en RAPPI COLOMBIA*DL 21:14. 12/03/2022 T.Cred *1234.",
+			expected: time.Date(2022, 3, 12, 21, 14, 0, 0, localLocation),
+			source:   synctypes.DateSourceBody,
+		},
+		{
+			text:     "Consignación por $300.000,00 en cta *5678 desde SUC. CHAPINERO. 14/03/2022 11:20.",
+			expected: time.Date(2022, 3, 14, 11, 20, 0, 0, localLocation),
+			source:   synctypes.DateSourceBody,
+		},
+		{
+			text:     "Cuota de manejo del 13/03/2022 en su T.Deb *5678.",
+			expected: time.Date(2022, 3, 13, 0, 0, 0, 0, localLocation),
+			source:   synctypes.DateSourceBody,
+		},
+		{
+			text:     "Cuota de manejo del 01/03/2022 en su T.Deb *5678.",
+			expected: envelopeDate,
+			source:   synctypes.DateSourceEnvelope,
+		},
+		{
+			text:     "Pago programado para el 31/03/2022 desde su cta *5678.",
+			expected: envelopeDate,
+			source:   synctypes.DateSourceEnvelope,
+		},
+		{
+			text:     "Pagaste $23.500 con código QR en PANADERIA LA 80.",
+			expected: envelopeDate,
+			source:   synctypes.DateSourceEnvelope,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			date, source := GetTransactionDate(test.text, envelopeDate)
+			if !date.Equal(test.expected) || source != test.source {
+				t.Errorf("got %s from %s, expected %s from %s", date, source, test.expected, test.source)
+			}
+		})
+	}
+}
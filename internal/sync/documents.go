@@ -0,0 +1,237 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/invoice"
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+// documentLookbackDays is how far back documents are looked for, so that the documents that match no purchase
+// are tried again in later runs, e.g. when they arrive before the purchase notification
+const documentLookbackDays = 7
+
+// documentsSince returns the date from which documents are looked for, without the ledger the applied documents
+// are not known so there is no lookback
+func documentsSince(ledger *Ledger, since time.Time) time.Time {
+	if ledger == nil {
+		return since
+	}
+	if lookback := time.Now().AddDate(0, 0, -documentLookbackDays); lookback.Before(since) {
+		return lookback
+	}
+	return since
+}
+
+// document is sent apart from the bank alert of a purchase, e.g. an electronic invoice or a merchant receipt, so
+// it can arrive after the purchase was booked
+type document interface {
+	kind() string
+	// key identifies the document in the ledger
+	key() string
+	total() types.Money
+	date() time.Time
+	// maxApart is how far apart the document and the purchase can be
+	maxApart() time.Duration
+	// matchesDescription tells if the entry can be of the purchase of the document
+	matchesDescription(description string) bool
+	summary() string
+}
+
+type invoiceDocument struct {
+	*types.Invoice
+}
+
+func (d invoiceDocument) kind() string                   { return "invoice" }
+func (d invoiceDocument) key() string                    { return d.SupplierNIT + "/" + d.Number }
+func (d invoiceDocument) total() types.Money             { return d.Total }
+func (d invoiceDocument) date() time.Time                { return d.IssueDate }
+func (d invoiceDocument) maxApart() time.Duration        { return invoice.MaxDaysApart * 24 * time.Hour }
+func (d invoiceDocument) matchesDescription(string) bool { return true }
+func (d invoiceDocument) summary() string                { return invoice.Summary(d.Invoice) }
+
+func (r merchantReceipt) kind() string { return "receipt" }
+func (r merchantReceipt) key() string {
+	return r.Receipt.Merchant + "/" + r.Receipt.Date.UTC().Format(time.RFC3339) + "/" + r.Receipt.Total.String()
+}
+func (r merchantReceipt) total() types.Money      { return r.Receipt.Total }
+func (r merchantReceipt) date() time.Time         { return r.Receipt.Date }
+func (r merchantReceipt) maxApart() time.Duration { return maxReceiptDistance }
+func (r merchantReceipt) matchesDescription(description string) bool {
+	return r.Enricher.MatchesPlace(description)
+}
+func (r merchantReceipt) summary() string { return r.Receipt.Details }
+
+// localDay returns the start of the day of the date, entries only keep the day
+func localDay(date time.Time) time.Time {
+	date = date.In(localLocation)
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, localLocation)
+}
+
+// documentRecorded tells if the ledger has the document, without a ledger no document is known
+func documentRecorded(ledger *Ledger, doc document) bool {
+	if ledger == nil {
+		return false
+	}
+
+	recorded, err := ledger.DocumentRecorded(doc.kind(), doc.key())
+	if err != nil {
+		logger.GetLogger().Errorw("could not read the ledger",
+			"document", doc.key(),
+			"error", err)
+		return false
+	}
+
+	return recorded
+}
+
+func entryHasDocument(ledger *Ledger, doc document, entryId string) bool {
+	has, err := ledger.EntryHasDocument(doc.kind(), entryId)
+	if err != nil {
+		logger.GetLogger().Errorw("could not read the ledger",
+			"entry", entryId,
+			"error", err)
+		return true
+	}
+
+	return has
+}
+
+func mappableAccountIds(mappableAccounts map[string]*toshl.Account) []string {
+	seen := make(map[string]bool)
+	var accountIds []string
+	for _, account := range mappableAccounts {
+		if !seen[account.ID] {
+			seen[account.ID] = true
+			accountIds = append(accountIds, account.ID)
+		}
+	}
+
+	return accountIds
+}
+
+// enrichExistingEntries adds the documents that match no purchase of the run to the closest existing expense with
+// the same amount, which does not have a document of the same kind yet. The ledger keeps which documents were
+// added, so without it existing entries are not changed
+func enrichExistingEntries(toshlClient toshl.ApiClient, ledger *Ledger, docs []document, mappableAccounts map[string]*toshl.Account) {
+	const DateFormat = "2006-01-02"
+
+	log := logger.GetLogger()
+
+	if len(docs) == 0 {
+		return
+	}
+	if ledger == nil {
+		log.Warnw("documents are only added to new purchases without the ledger",
+			"documents", len(docs))
+		return
+	}
+
+	from, to := docs[0].date().Add(-docs[0].maxApart()), docs[0].date().Add(docs[0].maxApart())
+	for _, doc := range docs {
+		if start := doc.date().Add(-doc.maxApart()); start.Before(from) {
+			from = start
+		}
+		if end := doc.date().Add(doc.maxApart()); end.After(to) {
+			to = end
+		}
+	}
+
+	entries, err := toshlClient.GetAccountsEntries(from.In(localLocation), to.In(localLocation), mappableAccountIds(mappableAccounts))
+	if err != nil {
+		log.Errorw("could not get the existing entries, documents are only added to new purchases",
+			"error", err)
+		return
+	}
+
+	used := make(map[*toshl.Entry]bool)
+	for _, doc := range docs {
+		var match *toshl.Entry
+		var matchApart time.Duration
+		for _, entry := range entries {
+			if used[entry] || entry.Id == nil || entry.Description == nil || entry.Amount >= 0 {
+				continue
+			}
+
+			amount, err := entry.Money()
+			if err != nil || amount.Abs() != doc.total() || !doc.matchesDescription(*entry.Description) {
+				continue
+			}
+
+			date, err := time.ParseInLocation(DateFormat, entry.Date, localLocation)
+			if err != nil {
+				continue
+			}
+
+			apart := timeApart(date, localDay(doc.date()))
+			if apart > doc.maxApart() || (match != nil && apart >= matchApart) {
+				continue
+			}
+			if entryHasDocument(ledger, doc, *entry.Id) {
+				continue
+			}
+
+			match, matchApart = entry, apart
+		}
+
+		if match == nil {
+			continue
+		}
+		used[match] = true
+
+		description := *match.Description + " - " + doc.summary()
+		match.Description = &description
+		if err := toshlClient.UpdateEntry(match); err != nil {
+			log.Errorw("could not add the document to the existing entry",
+				"kind", doc.kind(),
+				"document", doc.key(),
+				"entry", *match.Id,
+				"error", err)
+			continue
+		}
+
+		if err := ledger.RecordDocument(doc.kind(), doc.key(), *match.Id); err != nil {
+			log.Errorw("could not record the document in the ledger",
+				"kind", doc.kind(),
+				"document", doc.key(),
+				"error", err)
+		}
+
+		log.Infow("Added late document to existing entry",
+			"kind", doc.kind(),
+			"document", doc.key(),
+			"entry", *match.Id)
+	}
+}
+
+// RecordDocuments keeps in the ledger the documents added to the entries of the booked transactions
+func RecordDocuments(ledger *Ledger, transactions []*types.TransactionInfo) {
+	if ledger == nil {
+		return
+	}
+
+	for _, t := range transactions {
+		if len(t.EntryIds) == 0 {
+			continue
+		}
+
+		var docs []document
+		if t.Invoice != nil {
+			docs = append(docs, invoiceDocument{t.Invoice})
+		}
+		if t.Receipt != nil {
+			docs = append(docs, merchantReceipt{Receipt: t.Receipt})
+		}
+
+		for _, doc := range docs {
+			if err := ledger.RecordDocument(doc.kind(), doc.key(), t.EntryIds[0]); err != nil {
+				logger.GetLogger().Errorw("could not record the document in the ledger",
+					"kind", doc.kind(),
+					"document", doc.key(),
+					"error", err)
+			}
+		}
+	}
+}
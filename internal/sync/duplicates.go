@@ -0,0 +1,142 @@
+package sync
+
+import (
+	"strings"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+// fingerprint identifies an entry by its account, date and amount, the place is compared against the
+// description of the entries with the same fingerprint
+type fingerprint struct {
+	account string
+	date    string
+	amount  types.Money
+}
+
+func entryFingerprint(entry *toshl.Entry) (fingerprint, bool) {
+	amount, err := entry.Money()
+	if err != nil {
+		return fingerprint{}, false
+	}
+
+	return fingerprint{
+		account: entry.Account,
+		date:    entry.Date,
+		amount:  amount,
+	}, true
+}
+
+func normalizePlace(place string) string {
+	return strings.Join(strings.Fields(strings.ToLower(place)), " ")
+}
+
+// existingEntries returns the entries already in Toshl in the accounts and dates of the transactions, by
+// their fingerprint
+func existingEntries(toshlClient toshl.ApiClient, transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account) (map[fingerprint][]*toshl.Entry, error) {
+	existing := make(map[fingerprint][]*toshl.Entry)
+	if len(transactions) == 0 {
+		return existing, nil
+	}
+
+	from, to := transactions[0].Date, transactions[0].Date
+	accounts := make(map[string]bool)
+	var accountIds []string
+	for _, t := range transactions {
+		if t.Date.Before(from) {
+			from = t.Date
+		}
+		// monthly installments are booked in the following months
+		if last := addMonths(t.Date, t.Installments-1); t.Installments > 1 && last.After(to) {
+			to = last
+		} else if t.Date.After(to) {
+			to = t.Date
+		}
+
+		if account, ok := mappableAccounts[t.Account]; ok && !accounts[account.ID] {
+			accounts[account.ID] = true
+			accountIds = append(accountIds, account.ID)
+		}
+	}
+
+	entries, err := toshlClient.GetAccountsEntries(from.In(localLocation), to.In(localLocation), accountIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if key, ok := entryFingerprint(entry); ok {
+			existing[key] = append(existing[key], entry)
+		}
+	}
+
+	return existing, nil
+}
+
+// entryOriginFunc returns the key of the message that created the entry, when it is known
+type entryOriginFunc func(entryId string) (string, bool)
+
+// ledgerEntryOrigins looks the origin of the entries up in the ledger, the origin is unknown when there is no
+// ledger or it cannot be read
+func ledgerEntryOrigins(ledger *Ledger) entryOriginFunc {
+	log := logger.GetLogger()
+
+	return func(entryId string) (string, bool) {
+		if ledger == nil {
+			return "", false
+		}
+
+		key, ok, err := ledger.EntryOrigin(entryId)
+		if err != nil {
+			log.Errorw("could not read the origin of an entry from the ledger",
+				"entry", entryId,
+				"error", err)
+			return "", false
+		}
+
+		return key, ok
+	}
+}
+
+// findDuplicateEntry returns the existing entry with the fingerprint of the first entry of the transaction.
+// Entries whose message is known are only duplicates of a transaction of that same message, so identical
+// purchases made on the same day are not taken for each other. Entries of unknown origin, e.g. created before
+// the ledger, are duplicates when their description names the place of the transaction. Every existing entry
+// is the duplicate of one transaction at most
+func findDuplicateEntry(existing map[fingerprint][]*toshl.Entry, used map[*toshl.Entry]bool, t *types.TransactionInfo, newEntry *toshl.Entry, entryOrigin entryOriginFunc) *toshl.Entry {
+	key, ok := entryFingerprint(newEntry)
+	if !ok {
+		return nil
+	}
+
+	var places []string
+	for _, place := range []string{normalizePlace(t.Place), normalizePlace(placeName(t))} {
+		if place != "" {
+			places = append(places, place)
+		}
+	}
+
+	for _, entry := range existing[key] {
+		if used[entry] || entry.Id == nil || entry.Description == nil {
+			continue
+		}
+
+		if origin, known := entryOrigin(*entry.Id); known {
+			if origin == t.Origin.Key() {
+				return entry
+			}
+			continue
+		}
+
+		description := normalizePlace(*entry.Description)
+		for _, place := range places {
+			if strings.Contains(description, place) {
+				return entry
+			}
+		}
+	}
+
+	return nil
+}
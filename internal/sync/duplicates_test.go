@@ -0,0 +1,121 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+func TestFindDuplicateEntry(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+
+	value, _ := types.ParseMoney("COP", "38900")
+	purchase := &types.TransactionInfo{
+		Type:     "Compra",
+		Place:    "PAYU*NETFLIX BOGOTA",
+		Merchant: "Netflix",
+		Value:    value,
+		Account:  "1234",
+		Date:     time.Date(2022, 3, 14, 10, 0, 0, 0, localLocation),
+	}
+	newEntry := transactionEntries(purchase, account, types.InstallmentsModeFull)[0]
+
+	entries := []*toshl.Entry{
+		newTestEntry("other-account", "savings", "2022-03-14", "** Compra de Netflix", -38900),
+		newTestEntry("other-date", "card", "2022-03-13", "** Compra de Netflix", -38900),
+		newTestEntry("other-amount", "card", "2022-03-14", "** Compra de Netflix", -16900),
+		newTestEntry("other-place", "card", "2022-03-14", "** Compra de Spotify", -38900),
+		newTestEntry("duplicate", "card", "2022-03-14", "** Compra de  netflix - cuota 1 de 1", -38900),
+	}
+
+	existing := make(map[fingerprint][]*toshl.Entry)
+	for _, entry := range entries {
+		key, _ := entryFingerprint(entry)
+		existing[key] = append(existing[key], entry)
+	}
+
+	unknownOrigin := func(string) (string, bool) { return "", false }
+
+	used := make(map[*toshl.Entry]bool)
+	duplicate := findDuplicateEntry(existing, used, purchase, newEntry, unknownOrigin)
+	if duplicate == nil || *duplicate.Id != "duplicate" {
+		t.Fatalf("expected the duplicate entry, got %+v", duplicate)
+	}
+
+	// a second purchase identical to the first one is not a duplicate of the same entry
+	used[duplicate] = true
+	if duplicate := findDuplicateEntry(existing, used, purchase, newEntry, unknownOrigin); duplicate != nil {
+		t.Errorf("entries must be the duplicate of a single transaction, got %s", *duplicate.Id)
+	}
+}
+
+func TestFindDuplicateEntryOrigin(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+
+	value, _ := types.ParseMoney("COP", "8500")
+	coffee := &types.TransactionInfo{
+		Type:    "Compra",
+		Place:   "JUAN VALDEZ",
+		Value:   value,
+		Account: "1234",
+		Date:    time.Date(2022, 3, 14, 10, 0, 0, 0, localLocation),
+	}
+	newEntry := transactionEntries(coffee, account, types.InstallmentsModeFull)[0]
+
+	entry := newTestEntry("first-coffee", "card", "2022-03-14", "** Compra de JUAN VALDEZ", -8500)
+	key, _ := entryFingerprint(entry)
+	existing := map[fingerprint][]*toshl.Entry{key: {entry}}
+
+	origins := map[string]string{"first-coffee": "<first@bank>"}
+	entryOrigin := func(id string) (string, bool) {
+		origin, ok := origins[id]
+		return origin, ok
+	}
+
+	var tests = []struct {
+		name      string
+		messageId string
+		duplicate bool
+	}{
+		{name: "entry created by another message", messageId: "<second@bank>", duplicate: false},
+		{name: "entry created by the same message", messageId: "<first@bank>", duplicate: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			coffee.Origin = types.MessageOrigin{MessageId: test.messageId}
+
+			duplicate := findDuplicateEntry(existing, map[*toshl.Entry]bool{}, coffee, newEntry, entryOrigin)
+			if got := duplicate != nil; got != test.duplicate {
+				t.Errorf("expected duplicate to be %v, got %+v", test.duplicate, duplicate)
+			}
+		})
+	}
+}
+
+func TestFindDuplicateEntryWithoutPlace(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "savings"
+
+	value, _ := types.ParseMoney("COP", "50000")
+	withdrawal := &types.TransactionInfo{
+		Type:    "Retiro",
+		Value:   value,
+		Account: "5678",
+		Date:    time.Date(2022, 3, 14, 10, 0, 0, 0, localLocation),
+	}
+	newEntry := transactionEntries(withdrawal, account, types.InstallmentsModeFull)[0]
+
+	entry := newTestEntry("withdrawal", "savings", "2022-03-14", "** Retiro de ", -50000)
+	key, _ := entryFingerprint(entry)
+	existing := map[fingerprint][]*toshl.Entry{key: {entry}}
+
+	unknownOrigin := func(string) (string, bool) { return "", false }
+	if duplicate := findDuplicateEntry(existing, map[*toshl.Entry]bool{}, withdrawal, newEntry, unknownOrigin); duplicate != nil {
+		t.Errorf("a transaction without a place must not match any entry, got %s", *duplicate.Id)
+	}
+}
@@ -0,0 +1,45 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/gmf"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+// AddGMFTaxes adds the GMF to the debits of the configured accounts, the debits already booked in Toshl for
+// the month are the expenses of the account that are not taxes
+func AddGMFTaxes(toshlClient toshl.ApiClient, transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account, config types.GMFConfig, taxCategoryId string) error {
+	monthlyDebits := func(number string, month time.Time) (types.Money, error) {
+		total := types.Money{Currency: types.DefaultCurrencyCode}
+
+		account, ok := findMappableAccount(mappableAccounts, number)
+		if !ok {
+			return total, nil
+		}
+
+		from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, localLocation)
+		to := from.AddDate(0, 1, -1)
+		entries, err := toshlClient.GetAccountsEntries(from, to, []string{account.ID})
+		if err != nil {
+			return total, err
+		}
+
+		for _, entry := range entries {
+			if entry.Category == taxCategoryId || entry.Amount >= 0 {
+				continue
+			}
+
+			amount, err := entry.Money()
+			if err != nil || amount.Currency != total.Currency {
+				continue
+			}
+			total.Units -= amount.Units
+		}
+
+		return total, nil
+	}
+
+	return gmf.AddTaxes(transactions, config, monthlyDebits)
+}
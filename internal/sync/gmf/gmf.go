@@ -0,0 +1,110 @@
+package gmf
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+// TaxName is the name of the tax entries and of their category
+const TaxName = "GMF"
+
+// perThousand is the rate of the gravamen a los movimientos financieros, the 4x1000
+const perThousand = 4
+
+// MonthlyDebits returns the amount already debited from the account in the month of the given date
+type MonthlyDebits func(account string, month time.Time) (types.Money, error)
+
+// Tax returns the GMF of the amount, rounded half away from zero to the currency minor units
+func Tax(amount types.Money) types.Money {
+	units := amount.Abs().Units * perThousand
+	return types.Money{Units: (units + 500) / 1000, Currency: amount.Currency}
+}
+
+type monthKey struct {
+	account string
+	year    int
+	month   time.Month
+}
+
+// AddTaxes adds the GMF to the debits of the configured accounts. Debits from exempt accounts only pay the tax
+// on what goes over the exempt threshold in the month, counting what was already debited according to
+// monthlyDebits. When the debits cannot be read no tax is added
+func AddTaxes(transactions []*types.TransactionInfo, config types.GMFConfig, monthlyDebits MonthlyDebits) error {
+	accounts := make(map[string]types.GMFAccount, len(config.Accounts))
+	for _, a := range config.Accounts {
+		accounts[a.Number] = a
+	}
+
+	var threshold types.Money
+	if config.ExemptThreshold != "" {
+		var err error
+		threshold, err = types.ParseMoney(types.DefaultCurrencyCode, config.ExemptThreshold)
+		if err != nil {
+			return fmt.Errorf("invalid GMF exempt threshold: %w", err)
+		}
+	}
+
+	// exempt debits are counted in chronological order, so that the tax is paid by the ones that go over
+	// the threshold
+	sorted := make([]*types.TransactionInfo, len(transactions))
+	copy(sorted, transactions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	debited := make(map[monthKey]types.Money)
+	taxes := make(map[*types.TransactionInfo]types.Money)
+	for _, t := range sorted {
+		account, ok := accounts[t.Account]
+		if !ok || t.Direction != types.Debit || t.Value.Currency != types.DefaultCurrencyCode {
+			continue
+		}
+
+		taxable := t.Value
+		if account.Exempt {
+			date := t.Date.In(common.GetLocalLocation())
+			key := monthKey{account: t.Account, year: date.Year(), month: date.Month()}
+
+			total, ok := debited[key]
+			if !ok {
+				var err error
+				total, err = monthlyDebits(t.Account, date)
+				if err != nil {
+					return fmt.Errorf("failed to get the debits of account %s: %w", t.Account, err)
+				}
+			}
+
+			taxable = overThreshold(total, t.Value, threshold)
+			debited[key] = types.Money{Units: total.Units + t.Value.Units, Currency: total.Currency}
+		}
+
+		if tax := Tax(taxable); !tax.IsZero() {
+			taxes[t] = tax
+		}
+	}
+
+	for _, t := range transactions {
+		if tax, ok := taxes[t]; ok {
+			t.Taxes = append(t.Taxes, types.Tax{Name: TaxName, Value: tax})
+		}
+	}
+
+	return nil
+}
+
+// overThreshold returns the part of the value that goes over the threshold when it is added to total
+func overThreshold(total, value, threshold types.Money) types.Money {
+	free := threshold.Units - total.Units
+	if free < 0 {
+		free = 0
+	}
+	if free >= value.Units {
+		return types.Money{Currency: value.Currency}
+	}
+
+	return types.Money{Units: value.Units - free, Currency: value.Currency}
+}
@@ -0,0 +1,100 @@
+package gmf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+func newTransaction(account string, direction types.Direction, value string, day int) *types.TransactionInfo {
+	money, _ := types.ParseMoney("COP", value)
+	return &types.TransactionInfo{
+		Account:   account,
+		Direction: direction,
+		Value:     money,
+		Date:      time.Date(2022, 3, day, 10, 0, 0, 0, common.GetLocalLocation()),
+	}
+}
+
+func taxOf(t *types.TransactionInfo) string {
+	if len(t.Taxes) == 0 {
+		return ""
+	}
+	return t.Taxes[0].Value.Decimal()
+}
+
+func TestAddTaxes(t *testing.T) {
+	config := types.GMFConfig{
+		Accounts: []types.GMFAccount{
+			{Number: "1111"},
+			{Number: "2222", Exempt: true},
+		},
+		ExemptThreshold: "1000000",
+	}
+
+	liable := newTransaction("1111", types.Debit, "13900", 1)
+	credit := newTransaction("1111", types.Credit, "500000", 2)
+	notConfigured := newTransaction("3333", types.Debit, "50000", 3)
+	underThreshold := newTransaction("2222", types.Debit, "100000", 5)
+	overThreshold := newTransaction("2222", types.Debit, "300000", 6)
+	afterThreshold := newTransaction("2222", types.Debit, "100000", 7)
+
+	// in the wrong order on purpose, the threshold is counted in chronological order
+	transactions := []*types.TransactionInfo{afterThreshold, overThreshold, liable, credit, notConfigured, underThreshold}
+
+	alreadyDebited, _ := types.ParseMoney("COP", "800000")
+	monthlyDebits := func(account string, month time.Time) (types.Money, error) {
+		if account != "2222" || month.Month() != time.March {
+			t.Errorf("unexpected query for account %s in %s", account, month)
+		}
+		return alreadyDebited, nil
+	}
+
+	if err := AddTaxes(transactions, config, monthlyDebits); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cases := []struct {
+		name        string
+		transaction *types.TransactionInfo
+		expected    string
+	}{
+		{"liable", liable, "55.60"},
+		{"credit", credit, ""},
+		{"not configured", notConfigured, ""},
+		{"under threshold", underThreshold, ""},
+		{"over threshold", overThreshold, "800.00"},
+		{"after threshold", afterThreshold, "400.00"},
+	}
+	for _, c := range cases {
+		if tax := taxOf(c.transaction); tax != c.expected {
+			t.Errorf("%s: got tax [%s], expected [%s]", c.name, tax, c.expected)
+		}
+	}
+}
+
+func TestAddTaxesUnreadableDebits(t *testing.T) {
+	config := types.GMFConfig{
+		Accounts: []types.GMFAccount{
+			{Number: "1111"},
+			{Number: "2222", Exempt: true},
+		},
+	}
+
+	liable := newTransaction("1111", types.Debit, "13900", 1)
+	exempt := newTransaction("2222", types.Debit, "100000", 2)
+
+	monthlyDebits := func(string, time.Time) (types.Money, error) {
+		return types.Money{}, errors.New("unavailable")
+	}
+
+	if err := AddTaxes([]*types.TransactionInfo{liable, exempt}, config, monthlyDebits); err == nil {
+		t.Fatalf("expected an error when the debits cannot be read")
+	}
+	if len(liable.Taxes) != 0 || len(exempt.Taxes) != 0 {
+		t.Errorf("expected no tax to be added when the debits cannot be read")
+	}
+}
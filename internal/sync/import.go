@@ -0,0 +1,158 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/merchant"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+// bookedDateTolerance is how far apart the date of an alert and the date of its movement can be, banks post some
+// movements a few days after the alert
+const bookedDateTolerance = 3 * 24 * time.Hour
+
+// ImportStatus is the result of importing the movements of a file
+type ImportStatus struct {
+	SuccessfulTxs []*types.TransactionInfo
+	FailedTxs     []*types.TransactionInfo
+	// BookedTxs were already in Toshl, usually because their alerts were synced
+	BookedTxs []*types.TransactionInfo
+	// UnknownAccounts are the transactions whose account is not mapped to a Toshl account
+	UnknownAccounts []*types.ParseError
+}
+
+// SkipBookedTransactions splits the transactions into the ones that still have to be created and the ones that
+// already have an entry with the same account and amount on a close date, every entry matches a single transaction
+func SkipBookedTransactions(toshlClient toshl.ApiClient, transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account) ([]*types.TransactionInfo, []*types.TransactionInfo, error) {
+	if len(transactions) == 0 {
+		return nil, nil, nil
+	}
+
+	from, to := transactions[0].Date, transactions[0].Date
+	for _, t := range transactions {
+		if t.Date.Before(from) {
+			from = t.Date
+		}
+		if t.Date.After(to) {
+			to = t.Date
+		}
+	}
+
+	entries, err := toshlClient.GetEntries(from.Add(-bookedDateTolerance), to.Add(bookedDateTolerance))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	used := make(map[*toshl.Entry]bool)
+	var pending, booked []*types.TransactionInfo
+	for _, t := range transactions {
+		entry := findBookedEntry(entries, used, t, mappableAccounts[t.Account])
+		if entry == nil {
+			pending = append(pending, t)
+			continue
+		}
+
+		used[entry] = true
+		booked = append(booked, t)
+	}
+
+	return pending, booked, nil
+}
+
+func findBookedEntry(entries []*toshl.Entry, used map[*toshl.Entry]bool, t *types.TransactionInfo, account *toshl.Account) *toshl.Entry {
+	const DateFormat = "2006-01-02"
+
+	if account == nil {
+		return nil
+	}
+
+	amount := t.Value
+	if t.Direction == types.Debit {
+		amount = amount.Neg()
+	}
+
+	for _, entry := range entries {
+		if used[entry] || entry.Account != account.ID {
+			continue
+		}
+
+		entryAmount, err := entry.Money()
+		if err != nil || entryAmount != amount {
+			continue
+		}
+
+		date, err := time.ParseInLocation(DateFormat, entry.Date, localLocation)
+		if err != nil {
+			continue
+		}
+
+		day := time.Date(t.Date.Year(), t.Date.Month(), t.Date.Day(), 0, 0, 0, 0, localLocation)
+		diff := date.Sub(day)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= bookedDateTolerance {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// Import creates the entries of transactions read from a movements file, skipping the ones already booked. With
+// dryRun nothing is created and the transactions that would be created are returned as successful
+func Import(auth types.Auth, transactions []*types.TransactionInfo, dryRun bool) (ImportStatus, error) {
+	var status ImportStatus
+
+	log := logger.GetLogger()
+
+	if !auth.InstallmentsMode.Valid() {
+		return status, fmt.Errorf("unknown installments mode [%s]", auth.InstallmentsMode)
+	}
+
+	merchants, err := merchant.Load(auth.MerchantAliasesFile)
+	if err != nil {
+		return status, fmt.Errorf("failed to load merchant aliases: %w", err)
+	}
+
+	ruleSet, err := LoadRules(auth.CategoryRulesFile)
+	if err != nil {
+		return status, fmt.Errorf("failed to load category rules: %w", err)
+	}
+
+	toshlClient := toshl.NewApiClient(auth.ToshlToken)
+
+	accounts, err := toshlClient.GetAccounts()
+	if err != nil {
+		return status, err
+	}
+
+	var mappableAccounts map[string]*toshl.Account
+	mappableAccounts, transactions, status.UnknownAccounts = PrepareTransactions(toshlClient, auth, accounts, merchants, ruleSet, transactions)
+
+	transactions, status.BookedTxs, err = SkipBookedTransactions(toshlClient, transactions, mappableAccounts)
+	if err != nil {
+		return status, fmt.Errorf("failed to get the entries already booked: %w", err)
+	}
+
+	log.Infow("Imported transactions",
+		"pending", len(transactions),
+		"booked", len(status.BookedTxs),
+		"unknown_account", len(status.UnknownAccounts),
+	)
+
+	if dryRun {
+		status.SuccessfulTxs = transactions
+		return status, nil
+	}
+
+	categories := PrepareEntries(toshlClient, auth, transactions, mappableAccounts)
+	var duplicates []*types.TransactionInfo
+	status.SuccessfulTxs, status.FailedTxs, duplicates = CreateEntries(toshlClient, nil, transactions, mappableAccounts, categories, auth.InstallmentsMode)
+	status.BookedTxs = append(status.BookedTxs, duplicates...)
+
+	return status, nil
+}
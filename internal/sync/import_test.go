@@ -0,0 +1,42 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+func TestFindBookedEntry(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "savings"
+
+	value, _ := types.ParseMoney("COP", "13900")
+	movement := &types.TransactionInfo{
+		Place:     "COMPRA EN RAPPI COLOMBIA",
+		Value:     value,
+		Account:   "1234",
+		Date:      time.Date(2022, 3, 14, 0, 0, 0, 0, localLocation),
+		Direction: types.Debit,
+	}
+
+	entries := []*toshl.Entry{
+		newTestEntry("other-account", "card", "2022-03-12", "** Compra de RAPPI COLOMBIA*DL", -13900),
+		newTestEntry("other-amount", "savings", "2022-03-12", "** Compra de RAPPI COLOMBIA*DL", -13000),
+		newTestEntry("income", "savings", "2022-03-12", "** Compra de RAPPI COLOMBIA*DL", 13900),
+		newTestEntry("too-early", "savings", "2022-03-10", "** Compra de RAPPI COLOMBIA*DL", -13900),
+		newTestEntry("booked", "savings", "2022-03-12", "** Compra de RAPPI COLOMBIA*DL", -13900),
+	}
+
+	used := make(map[*toshl.Entry]bool)
+	entry := findBookedEntry(entries, used, movement, account)
+	if entry == nil || *entry.Id != "booked" {
+		t.Fatalf("expected the booked entry, got %+v", entry)
+	}
+
+	used[entry] = true
+	if entry := findBookedEntry(entries, used, movement, account); entry != nil {
+		t.Errorf("entries must match a single movement, got %s", *entry.Id)
+	}
+}
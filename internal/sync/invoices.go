@@ -0,0 +1,94 @@
+package sync
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap"
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/invoice"
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+func isZipAttachment(attachment imaptypes.Attachment) bool {
+	switch attachment.ContentType {
+	case "application/zip", "application/x-zip-compressed":
+		return true
+	}
+
+	return strings.EqualFold(filepath.Ext(attachment.Filename), ".zip")
+}
+
+func hasZipAttachment(msg imaptypes.Message) bool {
+	for _, attachment := range msg.Attachments {
+		if isZipAttachment(attachment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetInvoicesFromInbox returns the electronic invoices attached to the messages received since the given date
+func GetInvoicesFromInbox(mailClient imap.MailClient, since time.Time) ([]*types.Invoice, error) {
+	const inboxMailbox = "INBOX"
+
+	log := logger.GetLogger()
+
+	msgs, err := mailClient.GetMessages(inboxMailbox, since, hasZipAttachment)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoices []*types.Invoice
+	for _, msg := range msgs {
+		for _, attachment := range msg.Attachments {
+			if !isZipAttachment(attachment) {
+				continue
+			}
+
+			inv, err := invoice.ParseZip(attachment.Data)
+			if err != nil {
+				if !errors.Is(err, invoice.ErrNoInvoice) {
+					log.Warnw("could not parse electronic invoice",
+						"msgId", msg.SeqNum,
+						"filename", attachment.Filename,
+						"error", err)
+				}
+				continue
+			}
+
+			invoices = append(invoices, inv)
+		}
+	}
+
+	return invoices, nil
+}
+
+// PendingInvoices leaves out the invoices that were already added to an entry, invoices are looked for some
+// days back so the same ones are found in several runs
+func PendingInvoices(ledger *Ledger, invoices []*types.Invoice) []*types.Invoice {
+	var pending []*types.Invoice
+	for _, inv := range invoices {
+		if !documentRecorded(ledger, invoiceDocument{inv}) {
+			pending = append(pending, inv)
+		}
+	}
+
+	return pending
+}
+
+// EnrichWithInvoices sets the invoices of the purchases of the run and adds the ones that arrive after their
+// purchase was booked to the existing expense entry
+func EnrichWithInvoices(toshlClient toshl.ApiClient, ledger *Ledger, invoices []*types.Invoice, transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account) {
+	var docs []document
+	for _, inv := range invoice.Match(invoices, transactions) {
+		docs = append(docs, invoiceDocument{inv})
+	}
+
+	enrichExistingEntries(toshlClient, ledger, docs, mappableAccounts)
+}
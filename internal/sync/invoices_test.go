@@ -0,0 +1,101 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/invoice"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+func newTestInvoice(number, supplier, total string, issueDate time.Time) *types.Invoice {
+	value, _ := types.ParseMoney("COP", total)
+	return &types.Invoice{
+		Number:       number,
+		IssueDate:    issueDate,
+		SupplierNIT:  "890900608",
+		SupplierName: supplier,
+		Total:        value,
+	}
+}
+
+func TestEnrichWithInvoicesLateInvoice(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+	mappable := map[string]*toshl.Account{"1234": account}
+
+	issueDate := time.Date(2022, 3, 15, 9, 0, 0, 0, localLocation)
+	late := newTestInvoice("FE-1", "ALMACENES EXITO S.A.", "45000", issueDate)
+	other := newTestInvoice("FE-2", "PANAMERICANA S.A.S.", "32000", issueDate)
+	unmatched := newTestInvoice("FE-3", "FALABELLA S.A.", "99900", issueDate)
+
+	// the entry with an invoice in the ledger is not taken even though its description has none
+	purchase := newTestEntry("purchase", "card", "2022-03-14", "** Compra de EXITO", -45000)
+	enriched := newTestEntry("enriched", "card", "2022-03-14", "** Compra de PANAMERICANA", -32000)
+	client := &fakeToshlClient{entries: []*toshl.Entry{purchase, enriched}}
+
+	ledger := newTestLedger()
+	if err := ledger.RecordDocument("invoice", "890900608/FE-0", "enriched"); err != nil {
+		t.Fatal(err)
+	}
+
+	EnrichWithInvoices(client, ledger, []*types.Invoice{late, other, unmatched}, nil, mappable)
+
+	if len(client.updated) != 1 || *client.updated[0].Id != "purchase" {
+		t.Fatalf("expected only the purchase entry to be updated, got %d updates", len(client.updated))
+	}
+	if !strings.HasSuffix(*purchase.Description, " - "+invoice.Summary(late)) {
+		t.Errorf("expected the invoice in the description, got [%s]", *purchase.Description)
+	}
+
+	// the applied invoice is left out of later runs
+	pending := PendingInvoices(ledger, []*types.Invoice{late, other, unmatched})
+	if len(pending) != 2 || pending[0] != other || pending[1] != unmatched {
+		t.Errorf("expected only the invoices not applied to be pending, got %d", len(pending))
+	}
+	if has, _ := ledger.EntryHasDocument("invoice", "purchase"); !has {
+		t.Errorf("expected the purchase entry to have an invoice in the ledger")
+	}
+}
+
+func TestEnrichWithInvoicesWithoutLedger(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+	mappable := map[string]*toshl.Account{"1234": account}
+
+	issueDate := time.Date(2022, 3, 15, 9, 0, 0, 0, localLocation)
+	inv := newTestInvoice("FE-1", "ALMACENES EXITO S.A.", "45000", issueDate)
+	client := &fakeToshlClient{entries: []*toshl.Entry{newTestEntry("purchase", "card", "2022-03-14", "** Compra de EXITO", -45000)}}
+
+	EnrichWithInvoices(client, nil, []*types.Invoice{inv}, nil, mappable)
+
+	if len(client.updated) != 0 {
+		t.Errorf("expected no entry to be updated without the ledger, got %d", len(client.updated))
+	}
+}
+
+func TestEnrichWithInvoicesNewPurchase(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+	mappable := map[string]*toshl.Account{"1234": account}
+
+	issueDate := time.Date(2022, 3, 15, 9, 0, 0, 0, localLocation)
+	inv := newTestInvoice("FE-1", "ALMACENES EXITO S.A.", "45000", issueDate)
+
+	value, _ := types.ParseMoney("COP", "45000")
+	tx := &types.TransactionInfo{Type: "Compra", Place: "EXITO", Value: value, Direction: types.Debit, Account: "1234", Date: issueDate}
+
+	// an older entry with the same amount is not taken when the purchase is in the run
+	client := &fakeToshlClient{entries: []*toshl.Entry{newTestEntry("older", "card", "2022-03-14", "** Compra de EXITO", -45000)}}
+
+	EnrichWithInvoices(client, newTestLedger(), []*types.Invoice{inv}, []*types.TransactionInfo{tx}, mappable)
+
+	if tx.Invoice != inv {
+		t.Errorf("expected the invoice to be set to the purchase of the run")
+	}
+	if len(client.updated) != 0 {
+		t.Errorf("expected no entry to be updated, got %d", len(client.updated))
+	}
+}
@@ -0,0 +1,326 @@
+package sync
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/dynamodb"
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Outcome is what happened to a processed message
+type Outcome string
+
+const (
+	// OutcomeParsed is a message that does not book entries, e.g. a statement
+	OutcomeParsed Outcome = "parsed"
+	// OutcomeCreated is a message whose transaction was booked
+	OutcomeCreated Outcome = "created"
+	// OutcomeFailed is a message that could not be parsed or booked, it is processed again on the next runs up to
+	// maxFailedAttempts times
+	OutcomeFailed Outcome = "failed"
+	// OutcomeIgnored is a message that is not booked on purpose, e.g. of an unknown account or already booked
+	OutcomeIgnored Outcome = "ignored"
+)
+
+const (
+	ledgerTable = "toshl-ledger"
+
+	messageIdField   = "MessageId"
+	uidValidityField = "UIDValidity"
+	uidField         = "UID"
+	outcomeField     = "Outcome"
+	entryIdsField    = "EntryIds"
+	processedAtField = "ProcessedAt"
+	messageField     = "Message"
+	attemptsField    = "Attempts"
+	entryField       = "Entry"
+
+	// entryKeyPrefix is the prefix of the items that keep the message that created each entry
+	entryKeyPrefix = "entry:"
+	// documentEntrySuffix is added to the kind of a document in the items that mark the entries that have a
+	// document of that kind, e.g. "invoice-entry:<entry id>"
+	documentEntrySuffix = "-entry:"
+)
+
+// maxFailedAttempts is how many times a failed message is processed, after that it is left for the user, e.g. an
+// alert in a format that is not known yet would otherwise be notified in every run
+const maxFailedAttempts = 3
+
+// LedgerRecord is the outcome of a message, keyed by its Message-ID
+type LedgerRecord struct {
+	Origin   synctypes.MessageOrigin
+	Outcome  Outcome
+	EntryIds []string
+}
+
+// Ledger keeps the outcome of every processed message, so that messages are not processed twice even when the
+// last processed date does not move forward
+type Ledger struct {
+	client dynamodb.Client
+}
+
+func NewLedger() (*Ledger, error) {
+	client, err := dynamodb.NewClient("us-east-1")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ledger{client: client}, nil
+}
+
+func ledgerKey(key string) map[string]dynamodb.AttributeValue {
+	return map[string]dynamodb.AttributeValue{
+		messageIdField: {
+			AttributeValue: &types.AttributeValueMemberS{Value: key},
+		},
+	}
+}
+
+// Processed tells if the message has an outcome other than failed, or if it failed maxFailedAttempts times
+func (l *Ledger) Processed(origin synctypes.MessageOrigin) (bool, error) {
+	if origin.Key() == "" {
+		return false, nil
+	}
+
+	item, err := l.client.GetItem(ledgerTable, ledgerKey(origin.Key()))
+	if err != nil {
+		return false, err
+	}
+
+	outcome, ok := item[outcomeField].(string)
+	if !ok {
+		return false, nil
+	}
+
+	return Outcome(outcome) != OutcomeFailed || failedAttempts(item) >= maxFailedAttempts, nil
+}
+
+func failedAttempts(item map[string]interface{}) int {
+	value, _ := item[attemptsField].(string)
+	attempts, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+
+	return attempts
+}
+
+func (l *Ledger) Record(record LedgerRecord) error {
+	if record.Origin.Key() == "" {
+		return nil
+	}
+
+	attempts := 0
+	if record.Outcome == OutcomeFailed {
+		item, err := l.client.GetItem(ledgerTable, ledgerKey(record.Origin.Key()))
+		if err != nil {
+			return err
+		}
+		attempts = failedAttempts(item) + 1
+	}
+
+	expressionAttributeValues := map[string]dynamodb.AttributeValue{
+		":v": {
+			AttributeValue: &types.AttributeValueMemberN{Value: strconv.FormatUint(uint64(record.Origin.UIDValidity), 10)},
+		},
+		":u": {
+			AttributeValue: &types.AttributeValueMemberN{Value: strconv.FormatUint(uint64(record.Origin.UID), 10)},
+		},
+		":o": {
+			AttributeValue: &types.AttributeValueMemberS{Value: string(record.Outcome)},
+		},
+		":e": {
+			AttributeValue: &types.AttributeValueMemberS{Value: strings.Join(record.EntryIds, ",")},
+		},
+		":p": {
+			AttributeValue: &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC822Z)},
+		},
+		":a": {
+			AttributeValue: &types.AttributeValueMemberN{Value: strconv.Itoa(attempts)},
+		},
+	}
+
+	updateExpression := fmt.Sprintf("set %s = :v, %s = :u, %s = :o, %s = :e, %s = :p, %s = :a",
+		uidValidityField, uidField, outcomeField, entryIdsField, processedAtField, attemptsField)
+
+	err := l.client.UpdateItem(ledgerTable, ledgerKey(record.Origin.Key()), expressionAttributeValues, updateExpression)
+	if err != nil {
+		return err
+	}
+
+	for _, entryId := range record.EntryIds {
+		if err := l.recordEntryOrigin(entryId, record.Origin); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Ledger) recordEntryOrigin(entryId string, origin synctypes.MessageOrigin) error {
+	expressionAttributeValues := map[string]dynamodb.AttributeValue{
+		":m": {
+			AttributeValue: &types.AttributeValueMemberS{Value: origin.Key()},
+		},
+	}
+
+	updateExpression := fmt.Sprintf("set %s = :m", messageField)
+
+	return l.client.UpdateItem(ledgerTable, ledgerKey(entryKeyPrefix+entryId), expressionAttributeValues, updateExpression)
+}
+
+// EntryOrigin returns the key of the message that created the entry, it is unknown for the entries created by
+// hand or before the ledger existed
+func (l *Ledger) EntryOrigin(entryId string) (string, bool, error) {
+	item, err := l.client.GetItem(ledgerTable, ledgerKey(entryKeyPrefix+entryId))
+	if err != nil {
+		return "", false, err
+	}
+
+	key, ok := item[messageField].(string)
+	return key, ok && key != "", nil
+}
+
+// RecordDocument keeps that the document, e.g. an invoice, was added to the entry
+func (l *Ledger) RecordDocument(kind, key, entryId string) error {
+	expressionAttributeValues := map[string]dynamodb.AttributeValue{
+		":e": {
+			AttributeValue: &types.AttributeValueMemberS{Value: entryId},
+		},
+	}
+
+	updateExpression := fmt.Sprintf("set %s = :e", entryField)
+
+	if err := l.client.UpdateItem(ledgerTable, ledgerKey(kind+":"+key), expressionAttributeValues, updateExpression); err != nil {
+		return err
+	}
+
+	return l.client.UpdateItem(ledgerTable, ledgerKey(kind+documentEntrySuffix+entryId), expressionAttributeValues, updateExpression)
+}
+
+// DocumentRecorded tells if the document was already added to an entry
+func (l *Ledger) DocumentRecorded(kind, key string) (bool, error) {
+	return l.hasEntry(kind + ":" + key)
+}
+
+// EntryHasDocument tells if a document of the kind was already added to the entry
+func (l *Ledger) EntryHasDocument(kind, entryId string) (bool, error) {
+	return l.hasEntry(kind + documentEntrySuffix + entryId)
+}
+
+func (l *Ledger) hasEntry(key string) (bool, error) {
+	item, err := l.client.GetItem(ledgerTable, ledgerKey(key))
+	if err != nil {
+		return false, err
+	}
+
+	entryId, ok := item[entryField].(string)
+	return ok && entryId != "", nil
+}
+
+// isProcessed tells if the ledger has the message, messages are processed when the ledger cannot be read since
+// entries that already exist are skipped anyway
+func isProcessed(ledger *Ledger, msg imaptypes.Message) bool {
+	if ledger == nil {
+		return false
+	}
+
+	processed, err := ledger.Processed(synctypes.NewMessageOrigin(msg))
+	if err != nil {
+		logger.GetLogger().Errorw("could not read the ledger",
+			"msgId", msg.SeqNum,
+			"error", err)
+		return false
+	}
+
+	return processed
+}
+
+// SkipProcessedMessages leaves out the bank messages that were already processed
+func SkipProcessedMessages(ledger *Ledger, msgs []synctypes.BankMessage) []synctypes.BankMessage {
+	var pending []synctypes.BankMessage
+	for _, msg := range msgs {
+		if !isProcessed(ledger, msg.Message) {
+			pending = append(pending, msg)
+		}
+	}
+
+	return pending
+}
+
+// SkipProcessedMailMessages leaves out the messages that were already processed
+func SkipProcessedMailMessages(ledger *Ledger, msgs []imaptypes.Message) []imaptypes.Message {
+	var pending []imaptypes.Message
+	for _, msg := range msgs {
+		if !isProcessed(ledger, msg) {
+			pending = append(pending, msg)
+		}
+	}
+
+	return pending
+}
+
+// ledgerRecords returns the outcome of every message of the run
+func ledgerRecords(result txsStatus, pdfStatementMsgs []imaptypes.Message) []LedgerRecord {
+	var records []LedgerRecord
+	addTransactions := func(txs []*synctypes.TransactionInfo, outcome Outcome) {
+		for _, t := range txs {
+			records = append(records, LedgerRecord{Origin: t.Origin, Outcome: outcome, EntryIds: t.EntryIds})
+		}
+	}
+	addStatements := func(statements []*synctypes.StatementInfo, outcome Outcome) {
+		for _, s := range statements {
+			records = append(records, LedgerRecord{Origin: s.Origin, Outcome: outcome})
+		}
+	}
+
+	addTransactions(result.SuccessfulTxs, OutcomeCreated)
+	addTransactions(result.SkippedTxs, OutcomeIgnored)
+	addTransactions(result.FailedTxs, OutcomeFailed)
+	addStatements(result.Statements, OutcomeParsed)
+	addStatements(result.FailedStatements, OutcomeFailed)
+
+	failed := make(map[string]bool)
+	for _, e := range result.ParseErrors {
+		outcome := OutcomeFailed
+		if e.Reason == synctypes.ReasonUnknownAccount {
+			outcome = OutcomeIgnored
+		}
+		records = append(records, LedgerRecord{Origin: e.Origin, Outcome: outcome})
+		failed[e.Origin.Key()] = outcome == OutcomeFailed
+	}
+
+	// PDF statements are only reconciled, so they are parsed unless one of their attachments failed
+	for _, msg := range pdfStatementMsgs {
+		origin := synctypes.NewMessageOrigin(msg)
+		if !failed[origin.Key()] {
+			records = append(records, LedgerRecord{Origin: origin, Outcome: OutcomeParsed})
+		}
+	}
+
+	return records
+}
+
+// RecordOutcomes writes the outcome of every message of the run into the ledger
+func RecordOutcomes(ledger *Ledger, result txsStatus, pdfStatementMsgs []imaptypes.Message) {
+	if ledger == nil {
+		return
+	}
+
+	log := logger.GetLogger()
+
+	for _, record := range ledgerRecords(result, pdfStatementMsgs) {
+		if err := ledger.Record(record); err != nil {
+			log.Errorw("could not record the outcome of a message",
+				"message", record.Origin.Key(),
+				"outcome", record.Outcome,
+				"error", err)
+		}
+	}
+}
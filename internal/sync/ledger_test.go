@@ -0,0 +1,172 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/dynamodb"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	awstypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	_imap "github.com/emersion/go-imap"
+)
+
+// fakeDynamoClient keeps the items in memory, it only knows update expressions like "set A = :a, B = :b"
+type fakeDynamoClient struct {
+	items map[string]map[string]interface{}
+}
+
+func newTestLedger() *Ledger {
+	return &Ledger{client: &fakeDynamoClient{items: make(map[string]map[string]interface{})}}
+}
+
+func (c *fakeDynamoClient) Scan(string) ([]map[string]interface{}, error) {
+	var items []map[string]interface{}
+	for _, item := range c.items {
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (c *fakeDynamoClient) GetItem(_ string, key map[string]dynamodb.AttributeValue) (map[string]interface{}, error) {
+	return c.items[key[messageIdField].AttributeValue.(*awstypes.AttributeValueMemberS).Value], nil
+}
+
+func (c *fakeDynamoClient) UpdateItem(_ string, key map[string]dynamodb.AttributeValue, values map[string]dynamodb.AttributeValue, expression string) error {
+	id := key[messageIdField].AttributeValue.(*awstypes.AttributeValueMemberS).Value
+	item, ok := c.items[id]
+	if !ok {
+		item = map[string]interface{}{messageIdField: id}
+		c.items[id] = item
+	}
+
+	for _, assignment := range strings.Split(strings.TrimPrefix(expression, "set "), ",") {
+		parts := strings.SplitN(assignment, "=", 2)
+		switch value := values[strings.TrimSpace(parts[1])].AttributeValue.(type) {
+		case *awstypes.AttributeValueMemberS:
+			item[strings.TrimSpace(parts[0])] = value.Value
+		case *awstypes.AttributeValueMemberN:
+			item[strings.TrimSpace(parts[0])] = value.Value
+		}
+	}
+
+	return nil
+}
+
+func TestLedgerDocuments(t *testing.T) {
+	ledger := newTestLedger()
+
+	if err := ledger.RecordDocument("invoice", "890900608/FE-1", "entry-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if recorded, _ := ledger.DocumentRecorded("invoice", "890900608/FE-1"); !recorded {
+		t.Errorf("expected the invoice to be recorded")
+	}
+	if recorded, _ := ledger.DocumentRecorded("invoice", "890900608/FE-2"); recorded {
+		t.Errorf("expected other invoices not to be recorded")
+	}
+	if has, _ := ledger.EntryHasDocument("invoice", "entry-1"); !has {
+		t.Errorf("expected the entry to have an invoice")
+	}
+	if has, _ := ledger.EntryHasDocument("receipt", "entry-1"); has {
+		t.Errorf("expected the entry to have no receipt")
+	}
+}
+
+func TestLedgerFailedAttempts(t *testing.T) {
+	ledger := newTestLedger()
+	failed := LedgerRecord{Origin: types.MessageOrigin{MessageId: "unknown-format"}, Outcome: OutcomeFailed}
+
+	for attempt := 1; attempt <= maxFailedAttempts; attempt++ {
+		if processed, _ := ledger.Processed(failed.Origin); processed {
+			t.Fatalf("expected the message to be retried before attempt %d", attempt)
+		}
+		if err := ledger.Record(failed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if processed, _ := ledger.Processed(failed.Origin); !processed {
+		t.Errorf("expected the message to be given up after %d attempts", maxFailedAttempts)
+	}
+
+	// a message that is booked in a retry stays processed
+	retried := LedgerRecord{Origin: types.MessageOrigin{MessageId: "retried"}, Outcome: OutcomeFailed}
+	if err := ledger.Record(retried); err != nil {
+		t.Fatal(err)
+	}
+	retried.Outcome = OutcomeCreated
+	if err := ledger.Record(retried); err != nil {
+		t.Fatal(err)
+	}
+	if processed, _ := ledger.Processed(retried.Origin); !processed {
+		t.Errorf("expected the booked message to be processed")
+	}
+}
+
+func TestMessageOriginKey(t *testing.T) {
+	msg := imaptypes.Message{
+		Message:     &_imap.Message{Uid: 42, Envelope: &_imap.Envelope{MessageId: "<abc@notificacionesbancolombia.com>"}},
+		UIDValidity: 7,
+	}
+	if key := types.NewMessageOrigin(msg).Key(); key != "<abc@notificacionesbancolombia.com>" {
+		t.Errorf("expected the Message-ID as key, got [%s]", key)
+	}
+
+	msg.Envelope.MessageId = ""
+	if key := types.NewMessageOrigin(msg).Key(); key != "7/42" {
+		t.Errorf("expected the UIDVALIDITY and UID as key, got [%s]", key)
+	}
+
+	if key := (types.MessageOrigin{}).Key(); key != "" {
+		t.Errorf("expected no key for an unknown origin, got [%s]", key)
+	}
+}
+
+func TestLedgerRecords(t *testing.T) {
+	origin := func(id string) types.MessageOrigin {
+		return types.MessageOrigin{MessageId: id}
+	}
+
+	result := txsStatus{
+		SuccessfulTxs: []*types.TransactionInfo{{Origin: origin("created"), EntryIds: []string{"1", "2"}}},
+		SkippedTxs:    []*types.TransactionInfo{{Origin: origin("duplicate"), EntryIds: []string{"3"}}},
+		FailedTxs:     []*types.TransactionInfo{{Origin: origin("failed")}},
+		ParseErrors: []*types.ParseError{
+			{Origin: origin("unknown-account"), Reason: types.ReasonUnknownAccount},
+			{Origin: origin("bad-pdf"), Reason: types.ReasonUnexpected},
+		},
+		Statements: []*types.StatementInfo{{Origin: origin("statement")}},
+	}
+
+	pdfMsg := func(id string) imaptypes.Message {
+		return imaptypes.Message{Message: &_imap.Message{Envelope: &_imap.Envelope{MessageId: id}}}
+	}
+	pdfMsgs := []imaptypes.Message{pdfMsg("bad-pdf"), pdfMsg("pdf")}
+
+	expected := map[string]Outcome{
+		"created":         OutcomeCreated,
+		"duplicate":       OutcomeIgnored,
+		"failed":          OutcomeFailed,
+		"unknown-account": OutcomeIgnored,
+		"bad-pdf":         OutcomeFailed,
+		"statement":       OutcomeParsed,
+		"pdf":             OutcomeParsed,
+	}
+
+	records := ledgerRecords(result, pdfMsgs)
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %+v", len(expected), records)
+	}
+
+	for _, record := range records {
+		if outcome := expected[record.Origin.Key()]; record.Outcome != outcome {
+			t.Errorf("%s: expected outcome [%s], got [%s]", record.Origin.Key(), outcome, record.Outcome)
+		}
+	}
+
+	if entries := records[0].EntryIds; len(entries) != 2 {
+		t.Errorf("expected the entries of the created transaction, got %v", entries)
+	}
+}
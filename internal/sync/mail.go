@@ -1,19 +1,28 @@
 package sync
 
 import (
+	"time"
+
 	"github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap"
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
 	synctypes "github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
 )
 
-func GetEmailFromInbox(mailClient imap.MailClient, banks []synctypes.BankDelegate) ([]synctypes.BankMessage, error) {
+// ArchivedMailbox is where the messages are moved once they are synced
+const ArchivedMailbox = "Bancolombia"
+
+func GetEmailFromInbox(mailClient imap.MailClient, banks []synctypes.BankDelegate, since time.Time) ([]synctypes.BankMessage, error) {
 	const inboxMailbox = "INBOX"
 
-	since := GetLastProcessedDate()
+	return GetEmailFromMailbox(mailClient, inboxMailbox, banks, since)
+}
 
+// GetEmailFromMailbox returns the messages of the banks in the mailbox received since the given date
+func GetEmailFromMailbox(mailClient imap.MailClient, mailbox imaptypes.Mailbox, banks []synctypes.BankDelegate, since time.Time) ([]synctypes.BankMessage, error) {
 	var messages []synctypes.BankMessage
 
 	for _, bank := range banks {
-		msgs, err := mailClient.GetMessages(inboxMailbox, since, bank.FilterMessage)
+		msgs, err := mailClient.GetMessages(mailbox, since, bank.FilterMessage)
 		if err != nil {
 			return nil, err
 		}
@@ -31,20 +40,56 @@ func GetEmailFromInbox(mailClient imap.MailClient, banks []synctypes.BankDelegat
 	return messages, nil
 }
 
-func ArchiveEmailsOfSuccessfulTransactions(mailClient imap.MailClient, successfulTransactions []*synctypes.TransactionInfo) {
-	const archivedMailbox = "Bancolombia"
+// GetStatementsFromInbox returns the statement messages of the banks that send them
+func GetStatementsFromInbox(mailClient imap.MailClient, banks []synctypes.BankDelegate, since time.Time) ([]synctypes.BankMessage, error) {
+	const inboxMailbox = "INBOX"
+
+	var messages []synctypes.BankMessage
+
+	for _, bank := range banks {
+		statements, ok := bank.(synctypes.StatementDelegate)
+		if !ok {
+			continue
+		}
+
+		msgs, err := mailClient.GetMessages(inboxMailbox, since, statements.FilterStatementMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, msg := range msgs {
+			messages = append(messages, synctypes.BankMessage{
+				Message: msg,
+				Bank:    bank,
+			})
+		}
+	}
+
+	return messages, nil
+}
+
+// GetPDFStatementsFromInbox returns the messages with PDF statements of the configured senders
+func GetPDFStatementsFromInbox(mailClient imap.MailClient, config synctypes.PDFStatementsConfig, since time.Time) ([]imaptypes.Message, error) {
+	const inboxMailbox = "INBOX"
+
+	return mailClient.GetMessages(inboxMailbox, since, pdfStatementsFilter(config))
+}
+
+// ArchiveEmails moves the messages of the successful transactions and of the handled statements at once, since
+// moving messages changes the sequence numbers of the ones left
+func ArchiveEmails(mailClient imap.MailClient, successfulTransactions []*synctypes.TransactionInfo, statements []*synctypes.StatementInfo) {
 	mailboxes, err := mailClient.GetMailBoxes()
 	if err == nil {
 		found := false
 		for _, mailbox := range mailboxes {
-			if mailbox == archivedMailbox {
+			if mailbox == ArchivedMailbox {
 				found = true
 				break
 			}
 		}
 
 		if !found {
-			panic("archive mailbox not found " + archivedMailbox)
+			panic("archive mailbox not found " + ArchivedMailbox)
 		}
 	}
 
@@ -52,7 +97,10 @@ func ArchiveEmailsOfSuccessfulTransactions(mailClient imap.MailClient, successfu
 	for _, t := range successfulTransactions {
 		msgsIds = append(msgsIds, t.MsgId)
 	}
-	err = mailClient.Move(msgsIds, archivedMailbox)
+	for _, s := range statements {
+		msgsIds = append(msgsIds, s.MsgId)
+	}
+	err = mailClient.Move(msgsIds, ArchivedMailbox)
 	if err != nil {
 		panic(err)
 	}
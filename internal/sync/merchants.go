@@ -0,0 +1,30 @@
+package sync
+
+import (
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/merchant"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+// NormalizeMerchants sets the canonical merchant name of the transactions whose place matches an alias
+func NormalizeMerchants(merchants *merchant.Table, transactions []*types.TransactionInfo) {
+	log := logger.GetLogger()
+
+	for _, t := range transactions {
+		name, ok := merchants.Normalize(t.Place)
+		if !ok {
+			continue
+		}
+
+		log.Debugf("merchant of %s: %s", t.Place, name)
+		t.Merchant = name
+	}
+}
+
+// placeName is the name used for the place of the transaction in the entries, the merchant when it is known
+func placeName(t *types.TransactionInfo) string {
+	if t.Merchant != "" {
+		return t.Merchant
+	}
+	return t.Place
+}
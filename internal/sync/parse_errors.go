@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+type parseErrorGroup struct {
+	Reason types.ParseErrorReason
+	Errors []*types.ParseError
+}
+
+// details returns the distinct bank and field combinations of the group, e.g. "Bancolombia/place"
+func (g parseErrorGroup) details() []string {
+	seen := map[string]struct{}{}
+	var details []string
+	for _, e := range g.Errors {
+		detail := e.Bank
+		if e.Field != "" {
+			detail += "/" + e.Field
+		}
+
+		if _, ok := seen[detail]; !ok {
+			seen[detail] = struct{}{}
+			details = append(details, detail)
+		}
+	}
+
+	sort.Strings(details)
+	return details
+}
+
+func groupParseErrorsByReason(parseErrors []*types.ParseError) []parseErrorGroup {
+	byReason := map[types.ParseErrorReason][]*types.ParseError{}
+	for _, e := range parseErrors {
+		byReason[e.Reason] = append(byReason[e.Reason], e)
+	}
+
+	var groups []parseErrorGroup
+	for reason, errs := range byReason {
+		groups = append(groups, parseErrorGroup{Reason: reason, Errors: errs})
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Reason < groups[j].Reason
+	})
+
+	return groups
+}
+
+func logParseErrors(parseErrors []*types.ParseError) {
+	log := logger.GetLogger()
+
+	for _, group := range groupParseErrorsByReason(parseErrors) {
+		var msgIds []uint32
+		for _, e := range group.Errors {
+			msgIds = append(msgIds, e.MsgId)
+		}
+
+		log.Warnw("Had failures extracting information from messages",
+			"reason", group.Reason.String(),
+			"count", len(group.Errors),
+			"details", group.details(),
+			"msgIds", msgIds,
+		)
+	}
+}
+
+func parseErrorsSummary(parseErrors []*types.ParseError) []string {
+	var summary []string
+	for _, group := range groupParseErrorsByReason(parseErrors) {
+		summary = append(summary, fmt.Sprintf("parse %s: %d [%s]",
+			group.Reason, len(group.Errors), strings.Join(group.details(), ", ")))
+	}
+
+	return summary
+}
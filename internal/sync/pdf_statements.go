@@ -0,0 +1,123 @@
+package sync
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/importer"
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+const pdfStatementsSource = "PDF statement"
+
+func isPDFAttachment(attachment imaptypes.Attachment) bool {
+	return attachment.ContentType == "application/pdf" ||
+		strings.EqualFold(filepath.Ext(attachment.Filename), ".pdf") ||
+		importer.IsPDF(attachment.Data)
+}
+
+// pdfStatementsFilter keeps the messages of the configured senders that have PDF attachments
+func pdfStatementsFilter(config types.PDFStatementsConfig) imaptypes.Filter {
+	return func(msg imaptypes.Message) bool {
+		if msg.Message == nil || msg.Message.Envelope == nil {
+			return false
+		}
+
+		fromSender := false
+		for _, address := range msg.Message.Envelope.From {
+			for _, sender := range config.Senders {
+				fromSender = fromSender || strings.EqualFold(address.Address(), sender)
+			}
+		}
+		if !fromSender {
+			return false
+		}
+
+		for _, attachment := range msg.Attachments {
+			if isPDFAttachment(attachment) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// ExtractPDFStatementTransactions returns the movements of the PDF statements attached to the messages
+func ExtractPDFStatementTransactions(msgs []imaptypes.Message, config types.PDFStatementsConfig) ([]*types.TransactionInfo, []*types.ParseError) {
+	log := logger.GetLogger()
+
+	var transactions []*types.TransactionInfo
+	var parseErrors []*types.ParseError
+	for _, msg := range msgs {
+		for _, attachment := range msg.Attachments {
+			if !isPDFAttachment(attachment) {
+				continue
+			}
+
+			txs, err := extractPDFStatement(attachment, config)
+			if err != nil {
+				parseErr := &types.ParseError{
+					Bank:   pdfStatementsSource,
+					Reason: types.ReasonUnexpected,
+					MsgId:  msg.SeqNum,
+					Origin: types.NewMessageOrigin(msg),
+					Err:    fmt.Errorf("%s: %w", attachment.Filename, err),
+				}
+				log.Errorw("Error processing PDF statement",
+					"error", parseErr,
+					"msgId", msg.SeqNum,
+				)
+				parseErrors = append(parseErrors, parseErr)
+				continue
+			}
+
+			for _, t := range txs {
+				t.MsgId = msg.SeqNum
+				t.Origin = types.NewMessageOrigin(msg)
+			}
+			transactions = append(transactions, txs...)
+		}
+	}
+
+	return transactions, parseErrors
+}
+
+func extractPDFStatement(attachment imaptypes.Attachment, config types.PDFStatementsConfig) ([]*types.TransactionInfo, error) {
+	lines, err := importer.ReadPDF(bytes.NewReader(attachment.Data), int64(len(attachment.Data)), config.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	account, ok := importer.StatementAccount(lines)
+	if !ok {
+		return nil, fmt.Errorf("account number not found")
+	}
+
+	return importer.ParsePDFStatement(lines, account, importer.StatementSignConvention(config, account))
+}
+
+// ReconcilePDFStatements returns the movements of the PDF statements attached to the messages that are not booked
+// in Toshl. They are only reported, since the statements do not tell enough to create good entries, and can be
+// created with the import command
+func ReconcilePDFStatements(toshlClient toshl.ApiClient, msgs []imaptypes.Message, mappableAccounts map[string]*toshl.Account, config types.PDFStatementsConfig) ([]*types.TransactionInfo, []*types.ParseError, error) {
+	transactions, parseErrors := ExtractPDFStatementTransactions(msgs, config)
+
+	resolveAccountNumbers(transactions, mappableAccounts)
+
+	var unknownAccounts []*types.ParseError
+	transactions, unknownAccounts = FilterMappableTransactions(transactions, mappableAccounts)
+	parseErrors = append(parseErrors, unknownAccounts...)
+
+	missing, _, err := SkipBookedTransactions(toshlClient, transactions, mappableAccounts)
+	if err != nil {
+		return nil, parseErrors, err
+	}
+
+	return missing, parseErrors, nil
+}
@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/merchant"
+	"github.com/Philanthropists/toshl-email-autosync/internal/rules"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/gmf"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+// PrepareTransactions maps the transactions to the Toshl accounts, including the cash account, and sets their
+// merchant and category. It is the part of the pipeline shared by the sync and the import that does not change
+// Toshl, and returns the mappable accounts, the transactions of those accounts and the errors of the rest
+func PrepareTransactions(toshlClient toshl.ApiClient, auth types.Auth, accounts []*toshl.Account, merchants *merchant.Table, ruleSet *rules.RuleSet, transactions []*types.TransactionInfo) (map[string]*toshl.Account, []*types.TransactionInfo, []*types.ParseError) {
+	log := logger.GetLogger()
+
+	mappableAccounts := GetMappableAccounts(accounts)
+	if auth.CashAccount != "" && !AddCashAccount(mappableAccounts, accounts, auth.CashAccount) {
+		log.Warnw("cash account not found, withdrawals will be recorded as expenses",
+			"name", auth.CashAccount)
+	}
+
+	log.Debug("Mappable accounts")
+	for name, account := range mappableAccounts {
+		log.Debugf("%s: %s", name, account.Name)
+	}
+
+	resolveAccountNumbers(transactions, mappableAccounts)
+
+	var unknownAccounts []*types.ParseError
+	transactions, unknownAccounts = FilterMappableTransactions(transactions, mappableAccounts)
+
+	NormalizeMerchants(merchants, transactions)
+	Categorize(ruleSet, transactions)
+	if auth.Classifier.Enabled {
+		if model, err := LoadClassifier(toshlClient, auth.Classifier); err != nil {
+			log.Errorw("could not load the classifier, uncategorized entries stay PENDING",
+				"error", err)
+		} else {
+			Classify(model, transactions, classifierThreshold(auth.Classifier))
+		}
+	}
+
+	return mappableAccounts, transactions, unknownAccounts
+}
+
+// PrepareEntries adds the GMF and the tags to the transactions, creating the categories and tags that their
+// entries need. It is the part of the pipeline shared by the sync and the import that runs right before the
+// entries are created
+func PrepareEntries(toshlClient toshl.ApiClient, auth types.Auth, transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account) Categories {
+	log := logger.GetLogger()
+
+	categories := Categories{
+		Internal: CreateInternalCategoriesIfAbsent(toshlClient),
+		Taxes:    make(map[string]string),
+	}
+
+	if len(auth.GMF.Accounts) > 0 {
+		categories.Taxes[gmf.TaxName] = CreateCategoryIfAbsent(toshlClient, gmf.TaxName, expenseCategoryType)
+		if err := AddGMFTaxes(toshlClient, transactions, mappableAccounts, auth.GMF, categories.Taxes[gmf.TaxName]); err != nil {
+			log.Errorw("could not add the GMF taxes, the entries are created without them",
+				"error", err)
+		}
+	}
+
+	categories.Rules = CreateRuleCategoriesIfAbsent(toshlClient, transactions)
+	AddInstallmentsPlanTags(transactions, auth.InstallmentsMode)
+	if err := ResolveTags(toshlClient, transactions); err != nil {
+		log.Errorw("could not get some tags of the entries, they are created without them",
+			"error", err)
+	}
+
+	return categories
+}
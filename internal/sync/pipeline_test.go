@@ -0,0 +1,42 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/merchant"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+func TestPrepareTransactions(t *testing.T) {
+	newAccount := func(id, name string) *toshl.Account {
+		account := &toshl.Account{}
+		account.ID = id
+		account.Name = name
+		return account
+	}
+	accounts := []*toshl.Account{newAccount("savings", "3456 Ahorros"), newAccount("wallet", "Efectivo")}
+
+	merchants, err := merchant.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, _ := types.ParseMoney("COP", "200000")
+	// movements files write the complete account number
+	withdrawal := &types.TransactionInfo{Type: "Retiro", Place: "CAJERO", Value: value, Account: "0550123456", Direction: types.Debit, Counterpart: types.CashCounterpart}
+	unknown := &types.TransactionInfo{Type: "Compra", Place: "EXITO", Value: value, Account: "9999", Direction: types.Debit}
+
+	auth := types.Auth{CashAccount: "Efectivo"}
+	mappable, transactions, unknownAccounts := PrepareTransactions(nil, auth, accounts, merchants, nil, []*types.TransactionInfo{withdrawal, unknown})
+
+	if len(transactions) != 1 || transactions[0] != withdrawal || withdrawal.Account != "3456" {
+		t.Errorf("expected only the withdrawal of the savings account, got %d transactions", len(transactions))
+	}
+	if len(unknownAccounts) != 1 {
+		t.Errorf("expected the transaction of the unknown account to be reported, got %d", len(unknownAccounts))
+	}
+	if cash := mappable[types.CashCounterpart]; cash == nil || cash.ID != "wallet" {
+		t.Errorf("expected the cash account to be mappable")
+	}
+}
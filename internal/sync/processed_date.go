@@ -12,6 +12,7 @@ import (
 
 func GetLastProcessedDate() time.Time {
 	logger := logger.GetLogger()
+	const idField = "Id"
 	const dateField = "LastProcessedDate"
 	const tableName = "toshl-data"
 	defaultDate := time.Now().Add(-30 * 24 * time.Hour) // from 1 year in the past by default
@@ -29,25 +30,21 @@ func GetLastProcessedDate() time.Time {
 			"error", err)
 	}
 
-	res, err := client.Scan(tableName)
-	if err != nil || len(res) != 1 {
-		selectedDate = defaultDate
-		logger.Errorw("connection to dynamodb as unsuccessful",
-			"error", err)
+	// the table keeps other items too, e.g. the classifier model, so the date item is read by its id
+	key := map[string]dynamodb.AttributeValue{
+		idField: {
+			AttributeValue: &types.AttributeValueMemberN{Value: "1"},
+		},
 	}
 
-	if len(res) != 1 {
+	resValue, err := client.GetItem(tableName, key)
+	if err != nil {
 		selectedDate = defaultDate
-		logger.Warnw("something is wrong, the number of items retrieved was not 1",
-			"response", res,
-			"len", len(res))
-	}
-
-	if len(res) == 0 {
+		logger.Errorw("connection to dynamodb as unsuccessful",
+			"error", err)
 		return defaultDate
 	}
 
-	resValue := res[0]
 	value, ok := resValue[dateField]
 	if !ok {
 		selectedDate = defaultDate
@@ -0,0 +1,110 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap"
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+// maxReceiptDistance is how far apart the receipt and the purchase can be, receipts of deliveries are sent
+// when the order arrives
+const maxReceiptDistance = 24 * time.Hour
+
+type merchantReceipt struct {
+	Enricher types.Enricher
+	Receipt  *types.Receipt
+}
+
+// GetReceiptsFromInbox returns the receipts of the merchants of the enrichers, received since the given date
+func GetReceiptsFromInbox(mailClient imap.MailClient, enrichers []types.Enricher, since time.Time) ([]merchantReceipt, error) {
+	const inboxMailbox = "INBOX"
+
+	log := logger.GetLogger()
+
+	var receipts []merchantReceipt
+	for _, enricher := range enrichers {
+		msgs, err := mailClient.GetMessages(inboxMailbox, since, enricher.FilterMessage)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, msg := range msgs {
+			receipt, err := enricher.ExtractReceiptFromMessage(msg)
+			if err != nil {
+				log.Warnw("could not extract receipt",
+					"merchant", enricher.Name(),
+					"msgId", msg.SeqNum,
+					"error", err)
+				continue
+			}
+
+			receipts = append(receipts, merchantReceipt{Enricher: enricher, Receipt: receipt})
+		}
+	}
+
+	return receipts, nil
+}
+
+func timeApart(a, b time.Time) time.Duration {
+	if a.After(b) {
+		return a.Sub(b)
+	}
+	return b.Sub(a)
+}
+
+// PendingReceipts leaves out the receipts that were already added to an entry, receipts are looked for some days
+// back so the same ones are found in several runs
+func PendingReceipts(ledger *Ledger, receipts []merchantReceipt) []merchantReceipt {
+	var pending []merchantReceipt
+	for _, r := range receipts {
+		if !documentRecorded(ledger, r) {
+			pending = append(pending, r)
+		}
+	}
+
+	return pending
+}
+
+// MatchReceipts sets the receipt of the purchases paid to its merchant with the same amount, every receipt is set
+// to the closest purchase only. It returns the receipts that match no purchase
+func MatchReceipts(receipts []merchantReceipt, transactions []*types.TransactionInfo) []merchantReceipt {
+	var unmatched []merchantReceipt
+	for _, r := range receipts {
+		var match *types.TransactionInfo
+		for _, t := range transactions {
+			if t.Receipt != nil || t.Direction != types.Debit || t.Value != r.Receipt.Total || !r.Enricher.MatchesPlace(t.Place) {
+				continue
+			}
+
+			apart := timeApart(t.Date, r.Receipt.Date)
+			if apart > maxReceiptDistance {
+				continue
+			}
+			if match == nil || apart < timeApart(match.Date, r.Receipt.Date) {
+				match = t
+			}
+		}
+
+		if match == nil {
+			unmatched = append(unmatched, r)
+			continue
+		}
+		match.Receipt = r.Receipt
+	}
+
+	return unmatched
+}
+
+// EnrichWithReceipts sets the receipts of the purchases of the run and adds the ones that arrive after their
+// purchase was booked to the existing expense entry
+func EnrichWithReceipts(toshlClient toshl.ApiClient, ledger *Ledger, receipts []merchantReceipt, transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account) {
+	var docs []document
+	for _, r := range MatchReceipts(receipts, transactions) {
+		docs = append(docs, r)
+	}
+
+	enrichExistingEntries(toshlClient, ledger, docs, mappableAccounts)
+}
@@ -0,0 +1,88 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/enricher/rappi"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+func TestMatchReceipts(t *testing.T) {
+	value, _ := types.ParseMoney("COP", "45900")
+	other, _ := types.ParseMoney("COP", "12000")
+	date := time.Date(2022, 3, 14, 20, 0, 0, 0, localLocation)
+
+	newPurchase := func(place string, value types.Money, date time.Time) *types.TransactionInfo {
+		return &types.TransactionInfo{
+			Place:     place,
+			Value:     value,
+			Date:      date,
+			Direction: types.Debit,
+		}
+	}
+
+	otherMerchant := newPurchase("EXITO COLINA", value, date)
+	otherAmount := newPurchase("RAPPI COLOMBIA*DL", other, date)
+	tooLate := newPurchase("RAPPI COLOMBIA*DL", value, date.Add(48*time.Hour))
+	farther := newPurchase("RAPPI COLOMBIA*DL", value, date.Add(-3*time.Hour))
+	purchase := newPurchase("RAPPI COLOMBIA*DL", value, date.Add(-time.Hour))
+
+	receipt := &types.Receipt{
+		Merchant: "Rappi",
+		Total:    value,
+		Date:     date,
+		Details:  "Exito: 2 x Leche",
+	}
+	receipts := []merchantReceipt{{Enricher: rappi.Rappi{}, Receipt: receipt}}
+
+	txs := []*types.TransactionInfo{otherMerchant, otherAmount, tooLate, farther, purchase}
+	if unmatched := MatchReceipts(receipts, txs); len(unmatched) != 0 {
+		t.Errorf("expected every receipt to match, got %d unmatched", len(unmatched))
+	}
+
+	if purchase.Receipt != receipt {
+		t.Fatalf("expected the closest purchase to get the receipt")
+	}
+	for _, tx := range []*types.TransactionInfo{otherMerchant, otherAmount, tooLate, farther} {
+		if tx.Receipt != nil {
+			t.Errorf("unexpected receipt for %s %s", tx.Place, tx.Value)
+		}
+	}
+}
+
+func TestEnrichWithReceiptsLateReceipt(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+	mappable := map[string]*toshl.Account{"1234": account}
+
+	value, _ := types.ParseMoney("COP", "45900")
+	receipt := &types.Receipt{
+		Merchant: "Rappi",
+		Total:    value,
+		Date:     time.Date(2022, 3, 14, 21, 0, 0, 0, localLocation),
+		Details:  "Exito: 2 x Leche",
+	}
+	receipts := []merchantReceipt{{Enricher: rappi.Rappi{}, Receipt: receipt}}
+
+	otherMerchant := newTestEntry("exito", "card", "2022-03-14", "** Compra de EXITO COLINA", -45900)
+	purchase := newTestEntry("rappi", "card", "2022-03-14", "** Compra de RAPPI COLOMBIA*DL", -45900)
+	client := &fakeToshlClient{entries: []*toshl.Entry{otherMerchant, purchase}}
+	ledger := newTestLedger()
+
+	EnrichWithReceipts(client, ledger, receipts, nil, mappable)
+
+	if len(client.updated) != 1 || *client.updated[0].Id != "rappi" {
+		t.Fatalf("expected only the Rappi entry to be updated, got %d updates", len(client.updated))
+	}
+	if !strings.HasSuffix(*purchase.Description, " - "+receipt.Details) {
+		t.Errorf("expected the receipt in the description, got [%s]", *purchase.Description)
+	}
+
+	// the applied receipt is left out of later runs
+	if pending := PendingReceipts(ledger, receipts); len(pending) != 0 {
+		t.Errorf("expected the applied receipt not to be pending, got %d", len(pending))
+	}
+}
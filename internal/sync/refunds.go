@@ -0,0 +1,105 @@
+package sync
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+// refundLookback is how far back from a refund its original purchase is looked for
+const refundLookback = 90 * 24 * time.Hour
+
+// LinkRefunds looks in Toshl for the purchase that every refund gives back, it is matched by account and
+// amount, preferring purchases made in the same place and then the most recent one
+func LinkRefunds(toshlClient toshl.ApiClient, transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account) error {
+	log := logger.GetLogger()
+
+	var refunds []*types.TransactionInfo
+	for _, t := range transactions {
+		if t.Refund != nil {
+			refunds = append(refunds, t)
+		}
+	}
+
+	if len(refunds) == 0 {
+		return nil
+	}
+
+	from, to := refunds[0].Date, refunds[0].Date
+	for _, t := range refunds {
+		if t.Date.Before(from) {
+			from = t.Date
+		}
+		if t.Date.After(to) {
+			to = t.Date
+		}
+	}
+
+	entries, err := toshlClient.GetEntries(from.Add(-refundLookback).In(localLocation), to.In(localLocation))
+	if err != nil {
+		return err
+	}
+
+	for _, t := range refunds {
+		account, ok := mappableAccounts[t.Account]
+		if !ok {
+			continue
+		}
+
+		original, date := findRefundedEntry(entries, t, account)
+		if original == nil {
+			log.Infow("original purchase of refund not found",
+				"place", t.Place,
+				"value", t.Value.String())
+			continue
+		}
+
+		t.Refund.OriginalEntryId = *original.Id
+		t.Refund.OriginalDate = date
+	}
+
+	return nil
+}
+
+func findRefundedEntry(entries []*toshl.Entry, refund *types.TransactionInfo, account *toshl.Account) (*toshl.Entry, time.Time) {
+	const dateFormat = "2006-01-02"
+
+	refundDate := refund.Date.In(localLocation).Format(dateFormat)
+	place := strings.ToLower(refund.Place)
+	merchant := strings.ToLower(refund.Merchant)
+
+	var selected *toshl.Entry
+	var selectedDate time.Time
+	var selectedSamePlace bool
+	for _, entry := range entries {
+		if entry.Id == nil || entry.Account != account.ID || entry.Date > refundDate {
+			continue
+		}
+
+		amount, err := entry.Money()
+		if err != nil || amount != refund.Value.Neg() {
+			continue
+		}
+
+		date, err := time.ParseInLocation(dateFormat, entry.Date, localLocation)
+		if err != nil {
+			continue
+		}
+
+		samePlace := entry.Description != nil && strings.Contains(strings.ToLower(*entry.Description), place)
+		samePlace = samePlace || (merchant != "" && entry.Description != nil &&
+			strings.Contains(strings.ToLower(*entry.Description), merchant))
+
+		better := selected == nil
+		better = better || (samePlace && !selectedSamePlace)
+		better = better || (samePlace == selectedSamePlace && date.After(selectedDate))
+		if better {
+			selected, selectedDate, selectedSamePlace = entry, date, samePlace
+		}
+	}
+
+	return selected, selectedDate
+}
@@ -0,0 +1,82 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+	_toshl "github.com/Philanthropists/toshl-go"
+)
+
+func newTestEntry(id, account, date, description string, amount float64) *toshl.Entry {
+	entry := &toshl.Entry{}
+	entry.Id = &id
+	entry.Account = account
+	entry.Date = date
+	entry.Description = &description
+	entry.Amount = amount
+	entry.Currency = _toshl.Currency{Code: "COP"}
+	return entry
+}
+
+func TestFindRefundedEntry(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+
+	value, _ := types.ParseMoney("COP", "13900")
+	refund := &types.TransactionInfo{
+		Place:   "RAPPI COLOMBIA*DL",
+		Value:   value,
+		Account: "1234",
+		Date:    time.Date(2022, 3, 14, 10, 0, 0, 0, localLocation),
+		Refund:  &types.Refund{},
+	}
+
+	entries := []*toshl.Entry{
+		newTestEntry("other-account", "savings", "2022-03-12", "** Compra de RAPPI COLOMBIA*DL", -13900),
+		newTestEntry("other-amount", "card", "2022-03-12", "** Compra de RAPPI COLOMBIA*DL", -13000),
+		newTestEntry("after-refund", "card", "2022-03-15", "** Compra de RAPPI COLOMBIA*DL", -13900),
+		newTestEntry("other-place", "card", "2022-03-13", "** Compra de EXITO", -13900),
+		newTestEntry("original", "card", "2022-03-10", "** Compra de RAPPI COLOMBIA*DL", -13900),
+		newTestEntry("older", "card", "2022-02-10", "** Compra de RAPPI COLOMBIA*DL", -13900),
+	}
+
+	entry, date := findRefundedEntry(entries, refund, account)
+	if entry == nil {
+		t.Fatalf("expected to find the original entry")
+	}
+
+	if *entry.Id != "original" {
+		t.Errorf("got entry [%s], expected [original]", *entry.Id)
+	}
+
+	if date.Format("2006-01-02") != "2022-03-10" {
+		t.Errorf("got date [%s], expected [2022-03-10]", date)
+	}
+}
+
+func TestFindRefundedEntryByMerchant(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+
+	value, _ := types.ParseMoney("COP", "13900")
+	refund := &types.TransactionInfo{
+		Place:    "RAPPI COLOMBIA*DL",
+		Merchant: "Rappi",
+		Value:    value,
+		Account:  "1234",
+		Date:     time.Date(2022, 3, 14, 10, 0, 0, 0, localLocation),
+		Refund:   &types.Refund{},
+	}
+
+	entries := []*toshl.Entry{
+		newTestEntry("original", "card", "2022-03-10", "** Compra de Rappi", -13900),
+		newTestEntry("other-place", "card", "2022-03-13", "** Compra de Éxito", -13900),
+	}
+
+	entry, _ := findRefundedEntry(entries, refund, account)
+	if entry == nil || *entry.Id != "original" {
+		t.Fatalf("expected the entry of the merchant, got %+v", entry)
+	}
+}
@@ -0,0 +1,58 @@
+package sync
+
+import (
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/rules"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+// LoadRules loads the categorization rules, there are no rules when the file is not configured
+func LoadRules(path string) (*rules.RuleSet, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	return rules.LoadFile(path)
+}
+
+// Categorize sets the category and tags of the first rule that matches each transaction
+func Categorize(ruleSet *rules.RuleSet, transactions []*types.TransactionInfo) {
+	log := logger.GetLogger()
+
+	for _, t := range transactions {
+		rule, ok := ruleSet.Match(t)
+		if !ok {
+			continue
+		}
+
+		log.Debugf("rule [%s] matched %s: %s", rule.Name, t.Place, rule.Category)
+		t.Category = rule.Category
+		t.Tags = append([]string(nil), rule.Tags...)
+	}
+}
+
+// CreateRuleCategoriesIfAbsent returns the ids of the categories assigned by the rules, creating the ones
+// that do not exist yet
+func CreateRuleCategoriesIfAbsent(toshlClient toshl.ApiClient, transactions []*types.TransactionInfo) map[types.Direction]map[string]string {
+	categoryTypes := map[types.Direction]string{
+		types.Debit:  expenseCategoryType,
+		types.Credit: incomeCategoryType,
+	}
+
+	ids := make(map[types.Direction]map[string]string)
+	for _, t := range transactions {
+		if t.Category == "" {
+			continue
+		}
+
+		if ids[t.Direction] == nil {
+			ids[t.Direction] = make(map[string]string)
+		}
+		if _, ok := ids[t.Direction][t.Category]; !ok {
+			ids[t.Direction][t.Category] = CreateCategoryIfAbsent(toshlClient, t.Category, categoryTypes[t.Direction])
+		}
+	}
+
+	return ids
+}
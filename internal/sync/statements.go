@@ -0,0 +1,134 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+// defaultReminderDays is how many days before the due date statements are reminded when it is not configured
+const defaultReminderDays = 3
+
+// statementLookbackDays is how far back statements are looked for, the ones that are only notified stay in the
+// inbox until some days before they are due
+const statementLookbackDays = 45
+
+// statementsSince returns the date from which statements are looked for, it goes back far enough to find the
+// statements that are still waiting for their notification
+func statementsSince(since time.Time) time.Time {
+	if lookback := time.Now().AddDate(0, 0, -statementLookbackDays); lookback.Before(since) {
+		return lookback
+	}
+	return since
+}
+
+// isDueForReminder tells if the reminder days before the due date of the statement have started
+func isDueForReminder(s *types.StatementInfo, reminderDays uint, now time.Time) bool {
+	return !now.Before(s.DueDate.AddDate(0, 0, -int(reminderDays)))
+}
+
+func ExtractStatementInfoFromMessages(msgs []types.BankMessage) ([]*types.StatementInfo, []*types.ParseError) {
+	log := logger.GetLogger()
+	var parseErrors []*types.ParseError
+
+	var statements []*types.StatementInfo
+	for _, bankMsg := range msgs {
+		delegate, ok := bankMsg.Bank.(types.StatementDelegate)
+		if !ok {
+			continue
+		}
+
+		s, err := delegate.ExtractStatementInfoFromMessage(bankMsg.Message)
+		if err == nil {
+			s.Origin = types.NewMessageOrigin(bankMsg.Message)
+			statements = append(statements, s)
+			continue
+		}
+
+		parseErr := asParseError(bankMsg, err)
+		log.Errorw("Error processing statement message",
+			"error", parseErr,
+			"msgId", bankMsg.SeqNum,
+		)
+		parseErrors = append(parseErrors, parseErr)
+	}
+
+	return statements, parseErrors
+}
+
+// CreateStatementReminders creates a planned transfer of the statement balance, from the payment account into
+// the card account on the due date, that Toshl reminds some days before. Statements are only notified when the
+// payment account is not configured or the card is not mapped, and that notification is the reminder: until
+// some days before the due date they are returned as pending, so their messages are left in the inbox
+func CreateStatementReminders(toshlClient toshl.ApiClient, statements []*types.StatementInfo, accounts []*toshl.Account, mappableAccounts map[string]*toshl.Account, config types.StatementsConfig) ([]*types.StatementInfo, []*types.StatementInfo, []*types.StatementInfo) {
+	const DateFormat = "2006-01-02"
+
+	log := logger.GetLogger()
+
+	reminderDays := config.ReminderDays
+	if reminderDays == 0 {
+		reminderDays = defaultReminderDays
+	}
+	reminders := []toshl.Reminder{{Period: "day", Number: reminderDays}}
+
+	var paymentAccount *toshl.Account
+	if config.PaymentAccount != "" {
+		var ok bool
+		if paymentAccount, ok = findAccountByName(accounts, config.PaymentAccount); !ok {
+			log.Warnw("statements payment account not found, statements will only be notified",
+				"name", config.PaymentAccount)
+		}
+	}
+
+	now := time.Now()
+
+	var successfulStatements []*types.StatementInfo
+	var failedStatements []*types.StatementInfo
+	var pendingStatements []*types.StatementInfo
+	for _, s := range statements {
+		card, ok := findMappableAccount(mappableAccounts, s.Account)
+		if paymentAccount == nil || !ok {
+			if paymentAccount != nil {
+				log.Warnw("statement account is not mapped, it will only be notified",
+					"account", s.Account)
+			}
+
+			if !isDueForReminder(s, reminderDays, now) {
+				log.Infow("statement will be notified closer to its due date",
+					"account", s.Account,
+					"dueDate", s.DueDate)
+				pendingStatements = append(pendingStatements, s)
+				continue
+			}
+
+			successfulStatements = append(successfulStatements, s)
+			continue
+		}
+
+		var newEntry toshl.Entry
+		newEntry.SetAmount(s.Balance.Neg()) // negative because it leaves the payment account
+		newEntry.Date = s.DueDate.In(localLocation).Format(DateFormat)
+		description := fmt.Sprintf("** Pago de extracto *%s - pago mínimo %s", s.Account, s.MinimumPayment)
+		newEntry.Description = &description
+		newEntry.Account = paymentAccount.ID
+
+		transfer := toshl.Transfer{
+			Account:  card.ID,
+			Currency: newEntry.Currency,
+		}
+		if err := toshlClient.CreatePlannedTransfer(&newEntry, transfer, reminders); err != nil {
+			log.Errorf("Failed to create reminder for statement [%+v]: %s\n", s, err)
+			failedStatements = append(failedStatements, s)
+			continue
+		}
+
+		log.Infow("Created statement reminder successfully",
+			"entry", newEntry)
+		successfulStatements = append(successfulStatements, s)
+	}
+
+	return successfulStatements, failedStatements, pendingStatements
+}
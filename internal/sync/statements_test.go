@@ -0,0 +1,25 @@
+package sync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+)
+
+func TestCreateStatementRemindersWithoutPaymentAccount(t *testing.T) {
+	dueSoon := &types.StatementInfo{Account: "1234", DueDate: time.Now().AddDate(0, 0, 2)}
+	dueLater := &types.StatementInfo{Account: "5678", DueDate: time.Now().AddDate(0, 0, 15)}
+
+	successful, failed, pending := CreateStatementReminders(nil, []*types.StatementInfo{dueSoon, dueLater}, nil, nil, types.StatementsConfig{})
+
+	if len(failed) != 0 {
+		t.Errorf("expected no failed statements, got %d", len(failed))
+	}
+	if len(successful) != 1 || successful[0] != dueSoon {
+		t.Errorf("expected the statement due soon to be notified, got %+v", successful)
+	}
+	if len(pending) != 1 || pending[0] != dueLater {
+		t.Errorf("expected the statement due later to be pending, got %+v", pending)
+	}
+}
@@ -2,50 +2,86 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
-
 	"github.com/Philanthropists/toshl-email-autosync/internal/bank"
 	"github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap"
+	imaptypes "github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/enricher"
 	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
+	"github.com/Philanthropists/toshl-email-autosync/internal/merchant"
 	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
 	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
 	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
 )
 
-var localLocation *time.Location
+var localLocation = common.GetLocalLocation()
 
-func init() {
-	var err error
-	localLocation, err = time.LoadLocation("America/Bogota")
-	if err != nil {
-		panic(err)
+// asParseError wraps the errors that are not parse errors as unexpected ones, and sets the message id
+func asParseError(bankMsg types.BankMessage, err error) *types.ParseError {
+	var parseErr *types.ParseError
+	if !errors.As(err, &parseErr) {
+		parseErr = &types.ParseError{
+			Bank:   bankMsg.Bank.Name(),
+			Reason: types.ReasonUnexpected,
+			Err:    err,
+		}
 	}
+	parseErr.MsgId = bankMsg.SeqNum
+	parseErr.Origin = types.NewMessageOrigin(bankMsg.Message)
+
+	return parseErr
 }
 
-func ExtractTransactionInfoFromMessages(msgs []types.BankMessage) ([]*types.TransactionInfo, int64) {
+func ExtractTransactionInfoFromMessages(msgs []types.BankMessage) ([]*types.TransactionInfo, []*types.ParseError) {
 	log := logger.GetLogger()
-	var failures int64
+	var parseErrors []*types.ParseError
 
 	var transactions []*types.TransactionInfo
 	for _, bankMsg := range msgs {
 		t, err := bankMsg.Bank.ExtractTransactionInfoFromMessage(bankMsg.Message)
 		if err == nil {
+			t.Origin = types.NewMessageOrigin(bankMsg.Message)
 			transactions = append(transactions, t)
-		} else {
-			log.Errorw("Error processing message",
-				"error", err,
-				"msgId", bankMsg.SeqNum,
-			)
-			failures++
+			continue
 		}
+
+		parseErr := asParseError(bankMsg, err)
+		log.Errorw("Error processing message",
+			"error", parseErr,
+			"msgId", bankMsg.SeqNum,
+		)
+		parseErrors = append(parseErrors, parseErr)
 	}
 
-	return transactions, failures
+	return transactions, parseErrors
+}
+
+// FilterMappableTransactions separates the transactions that belong to an account that is not mapped to Toshl
+func FilterMappableTransactions(transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account) ([]*types.TransactionInfo, []*types.ParseError) {
+	var mappable []*types.TransactionInfo
+	var parseErrors []*types.ParseError
+	for _, t := range transactions {
+		if _, ok := mappableAccounts[t.Account]; ok {
+			mappable = append(mappable, t)
+			continue
+		}
+
+		var bankName string
+		if t.Bank != nil {
+			bankName = t.Bank.Name()
+		}
+
+		parseErr := types.NewUnknownAccountError(bankName, t.Account)
+		parseErr.MsgId = t.MsgId
+		parseErr.Origin = t.Origin
+		parseErrors = append(parseErrors, parseErr)
+	}
+
+	return mappable, parseErrors
 }
 
 func getEarliestDateFromTxs(txs []*types.TransactionInfo) time.Time {
@@ -60,40 +96,97 @@ func getEarliestDateFromTxs(txs []*types.TransactionInfo) time.Time {
 	return earliestDate
 }
 
-const notificationFormat = `%s Transactions: s:%d / f:%d / parse:%d`
+const notificationFormat = `%s Transactions: s:%d / f:%d / skip:%d / parse:%d`
 
 type txsStatus struct {
 	SuccessfulTxs []*types.TransactionInfo
 	FailedTxs     []*types.TransactionInfo
-	ParseFailures int64
+	// SkippedTxs already had an entry in Toshl
+	SkippedTxs  []*types.TransactionInfo
+	ParseErrors []*types.ParseError
+
+	Statements       []*types.StatementInfo
+	FailedStatements []*types.StatementInfo
+
+	// MissingTxs are the movements of PDF statements that are not booked in Toshl
+	MissingTxs []*types.TransactionInfo
 }
 
-func notificationString(success, failures []*types.TransactionInfo, parseFails int64) string {
-	versionInfo := common.GetVersion()[:4]
-	msg := fmt.Sprintf(notificationFormat, versionInfo, len(success), len(failures), parseFails)
+func notificationString(result txsStatus) string {
+	success, failures, parseErrors := result.SuccessfulTxs, result.FailedTxs, result.ParseErrors
 
-	p := message.NewPrinter(language.English)
+	versionInfo := common.GetVersion()[:4]
+	msg := fmt.Sprintf(notificationFormat, versionInfo, len(success), len(failures), len(result.SkippedTxs), len(parseErrors))
 
-	const txsFormat = `%s || $ %.2f %s|| %s`
+	const txsFormat = `%s || %s %s|| %s`
 	const dateFormat = "2006-01-02"
 	var status []string
 	status = append(status, msg)
+	status = append(status, parseErrorsSummary(parseErrors)...)
 
 	for _, txs := range success {
 		status = append(status,
-			p.Sprintf(txsFormat,
+			fmt.Sprintf(txsFormat,
 				txs.Date.Format(dateFormat),
-				*txs.Value.Rate,
+				txs.Value,
 				txs.Place,
 				"SUCCESS"))
+
+		for _, tax := range txs.FailedTaxes {
+			status = append(status,
+				fmt.Sprintf(txsFormat,
+					txs.Date.Format(dateFormat),
+					tax.Value,
+					tax.Name+" de "+txs.Place,
+					"TAX FAILED"))
+		}
 	}
 
 	for _, txs := range failures {
 		status = append(status,
-			p.Sprintf(txsFormat,
+			fmt.Sprintf(txsFormat,
+				txs.Date.Format(dateFormat),
+				txs.Value,
+				txs.Place,
+				"FAILED"))
+	}
+
+	for _, txs := range result.SkippedTxs {
+		status = append(status,
+			fmt.Sprintf(txsFormat,
+				txs.Date.Format(dateFormat),
+				txs.Value,
+				txs.Place,
+				"SKIPPED"))
+	}
+
+	for _, txs := range result.MissingTxs {
+		status = append(status,
+			fmt.Sprintf(txsFormat,
 				txs.Date.Format(dateFormat),
-				*txs.Value.Rate,
+				txs.Value,
 				txs.Place,
+				"MISSING"))
+	}
+
+	const statementFormat = `%s || extracto *%s || total %s / min %s || %s`
+	for _, s := range result.Statements {
+		status = append(status,
+			fmt.Sprintf(statementFormat,
+				s.DueDate.Format(dateFormat),
+				s.Account,
+				s.Balance,
+				s.MinimumPayment,
+				"DUE"))
+	}
+
+	for _, s := range result.FailedStatements {
+		status = append(status,
+			fmt.Sprintf(statementFormat,
+				s.DueDate.Format(dateFormat),
+				s.Account,
+				s.Balance,
+				s.MinimumPayment,
 				"FAILED"))
 	}
 
@@ -107,23 +200,55 @@ func Run(ctx context.Context, auth types.Auth) error {
 	defer log.Sync()
 
 	defer func() {
+		logParseErrors(status.ParseErrors)
+
 		log.Infow("Synced transactions",
 			"successful", len(status.SuccessfulTxs),
 			"failed", len(status.FailedTxs),
-			"failed_to_parse", status.ParseFailures,
+			"skipped", len(status.SkippedTxs),
+			"failed_to_parse", len(status.ParseErrors),
+			"statements", len(status.Statements),
+			"failed_statements", len(status.FailedStatements),
+			"missing", len(status.MissingTxs),
 		)
 
-		shouldNotify := status.ParseFailures > 0
+		shouldNotify := len(status.ParseErrors) > 0
 		shouldNotify = shouldNotify || len(status.FailedTxs) > 0
 		shouldNotify = shouldNotify || len(status.SuccessfulTxs) > 0
+		shouldNotify = shouldNotify || len(status.SkippedTxs) > 0
+		shouldNotify = shouldNotify || len(status.Statements) > 0
+		shouldNotify = shouldNotify || len(status.FailedStatements) > 0
+		shouldNotify = shouldNotify || len(status.MissingTxs) > 0
 
 		if shouldNotify && auth.TwilioAccountSid != "" {
-			msg := notificationString(status.SuccessfulTxs, status.FailedTxs, status.ParseFailures)
+			msg := notificationString(status)
 			SendNotifications(auth, msg)
 		}
 	}()
 
+	if !auth.InstallmentsMode.Valid() {
+		return fmt.Errorf("unknown installments mode [%s]", auth.InstallmentsMode)
+	}
+
 	banks := bank.GetBanks()
+	if auth.BankDefinitionsDir != "" {
+		definitions, err := bank.LoadDefinitions(auth.BankDefinitionsDir)
+		if err != nil {
+			return fmt.Errorf("failed to load bank definitions: %w", err)
+		}
+
+		banks = append(banks[:len(banks):len(banks)], definitions...)
+	}
+
+	merchants, err := merchant.Load(auth.MerchantAliasesFile)
+	if err != nil {
+		return fmt.Errorf("failed to load merchant aliases: %w", err)
+	}
+
+	ruleSet, err := LoadRules(auth.CategoryRulesFile)
+	if err != nil {
+		return fmt.Errorf("failed to load category rules: %w", err)
+	}
 
 	mailClient, err := imap.GetMailClient(auth.Addr, auth.Username, auth.Password)
 	if err != nil {
@@ -131,21 +256,65 @@ func Run(ctx context.Context, auth types.Auth) error {
 	}
 	defer mailClient.Logout()
 
-	msgs, err := GetEmailFromInbox(mailClient, banks)
+	since := GetLastProcessedDate()
+
+	ledger, err := NewLedger()
+	if err != nil {
+		log.Errorw("could not open the ledger, messages are processed without checking it",
+			"error", err)
+		ledger = nil
+	}
+
+	msgs, err := GetEmailFromInbox(mailClient, banks, since)
 	if err != nil {
 		return err
 	}
+	msgs = SkipProcessedMessages(ledger, msgs)
 
 	var transactions []*types.TransactionInfo
-	transactions, status.ParseFailures = ExtractTransactionInfoFromMessages(msgs)
+	transactions, status.ParseErrors = ExtractTransactionInfoFromMessages(msgs)
 
-	if status.ParseFailures > 0 {
-		log.Infow("Had failures extracting information from messages",
-			"failures", status.ParseFailures,
-		)
+	statementMsgs, err := GetStatementsFromInbox(mailClient, banks, statementsSince(since))
+	if err != nil {
+		return err
+	}
+	statementMsgs = SkipProcessedMessages(ledger, statementMsgs)
+
+	statements, statementParseErrors := ExtractStatementInfoFromMessages(statementMsgs)
+	status.ParseErrors = append(status.ParseErrors, statementParseErrors...)
+
+	var pdfStatementMsgs []imaptypes.Message
+	if len(auth.PDFStatements.Senders) > 0 {
+		pdfStatementMsgs, err = GetPDFStatementsFromInbox(mailClient, auth.PDFStatements, since)
+		if err != nil {
+			return err
+		}
+		pdfStatementMsgs = SkipProcessedMailMessages(ledger, pdfStatementMsgs)
+	}
+
+	// invoices and receipts are looked for even without new alerts, they can belong to purchases booked in
+	// previous runs
+	var invoices []*types.Invoice
+	if auth.EnrichWithInvoices {
+		invoices, err = GetInvoicesFromInbox(mailClient, documentsSince(ledger, since))
+		if err != nil {
+			log.Errorw("could not get electronic invoices",
+				"error", err)
+		}
+		invoices = PendingInvoices(ledger, invoices)
+	}
+
+	var receipts []merchantReceipt
+	if auth.EnrichWithReceipts {
+		receipts, err = GetReceiptsFromInbox(mailClient, enricher.GetEnrichers(), documentsSince(ledger, since))
+		if err != nil {
+			log.Errorw("could not get merchant receipts",
+				"error", err)
+		}
+		receipts = PendingReceipts(ledger, receipts)
 	}
 
-	if len(transactions) == 0 {
+	if len(transactions) == 0 && len(statements) == 0 && len(pdfStatementMsgs) == 0 && len(invoices) == 0 && len(receipts) == 0 {
 		log.Info("no transactions to process, exiting ... ")
 		return nil
 	}
@@ -156,7 +325,6 @@ func Run(ctx context.Context, auth types.Auth) error {
 	}
 
 	toshlClient := toshl.NewApiClient(auth.ToshlToken)
-	internalCategoryId := CreateInternalCategoryIfAbsent(toshlClient)
 
 	accounts, err := toshlClient.GetAccounts()
 	if err != nil {
@@ -168,16 +336,40 @@ func Run(ctx context.Context, auth types.Auth) error {
 		log.Debugf("%d: %s", i, a.Name)
 	}
 
-	mappableAccounts := GetMappableAccounts(accounts)
+	mappableAccounts, transactions, unknownAccounts := PrepareTransactions(toshlClient, auth, accounts, merchants, ruleSet, transactions)
+	status.ParseErrors = append(status.ParseErrors, unknownAccounts...)
 
-	log.Debug("Mappable accounts")
-	for name, account := range mappableAccounts {
-		log.Debugf("%s: %s", name, account.Name)
+	if err := LinkRefunds(toshlClient, transactions, mappableAccounts); err != nil {
+		log.Errorw("could not look for the original purchases of refunds",
+			"error", err)
 	}
 
-	status.SuccessfulTxs, status.FailedTxs = CreateEntries(toshlClient, transactions, mappableAccounts, internalCategoryId)
+	EnrichWithInvoices(toshlClient, ledger, invoices, transactions, mappableAccounts)
+
+	EnrichWithReceipts(toshlClient, ledger, receipts, transactions, mappableAccounts)
+
+	categories := PrepareEntries(toshlClient, auth, transactions, mappableAccounts)
+	status.SuccessfulTxs, status.FailedTxs, status.SkippedTxs = CreateEntries(toshlClient, ledger, transactions, mappableAccounts, categories, auth.InstallmentsMode)
+
+	if len(pdfStatementMsgs) > 0 {
+		var pdfParseErrors []*types.ParseError
+		status.MissingTxs, pdfParseErrors, err = ReconcilePDFStatements(toshlClient, pdfStatementMsgs, mappableAccounts, auth.PDFStatements)
+		status.ParseErrors = append(status.ParseErrors, pdfParseErrors...)
+		if err != nil {
+			log.Errorw("could not reconcile PDF statements",
+				"error", err)
+		}
+	}
+
+	// pending statements are neither recorded nor archived, they are notified in a later run
+	status.Statements, status.FailedStatements, _ = CreateStatementReminders(toshlClient, statements, accounts, mappableAccounts, auth.Statements)
+
+	RecordOutcomes(ledger, status, pdfStatementMsgs)
+	RecordDocuments(ledger, status.SuccessfulTxs)
 
-	ArchiveEmailsOfSuccessfulTransactions(mailClient, status.SuccessfulTxs)
+	// skipped transactions are archived too, their entries were created before
+	archivedTxs := append(status.SuccessfulTxs[:len(status.SuccessfulTxs):len(status.SuccessfulTxs)], status.SkippedTxs...)
+	ArchiveEmails(mailClient, archivedTxs, status.Statements)
 
 	if err := UpdateLastProcessedDate(status.FailedTxs); err != nil {
 		return fmt.Errorf("failed to update last processed date: %s", err)
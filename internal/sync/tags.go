@@ -0,0 +1,131 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+var cardTagNames = map[types.CardType]string{
+	types.CreditCard: "crédito",
+	types.DebitCard:  "débito",
+}
+
+// automaticTags are the names of the tags of every entry: its bank, the kind of card and the transaction type
+func automaticTags(t *types.TransactionInfo) []string {
+	var names []string
+	if t.Bank != nil {
+		names = append(names, strings.ToLower(t.Bank.Name()))
+	}
+	if name, ok := cardTagNames[t.Card]; ok {
+		names = append(names, name)
+	}
+	if t.Type != "" {
+		names = append(names, strings.ToLower(t.Type))
+	}
+
+	return names
+}
+
+// AddInstallmentsPlanTags adds the tag of the plan, e.g. "12 cuotas", to the purchases in installments that are
+// booked with InstallmentsModePlan, so that the purchases of a plan can be found by tag
+func AddInstallmentsPlanTags(transactions []*types.TransactionInfo, installmentsMode types.InstallmentsMode) {
+	if installmentsMode != types.InstallmentsModePlan {
+		return
+	}
+
+	for _, t := range transactions {
+		if t.Installments <= 1 {
+			continue
+		}
+
+		if tag := fmt.Sprintf("%d cuotas", t.Installments); !containsString(t.Tags, tag) {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+}
+
+type tagKey struct {
+	tagType string
+	name    string
+}
+
+// transactionTagKeys returns the keys of the automatic tags and the tags of the rules of the transaction, with
+// the names they are created with
+func transactionTagKeys(t *types.TransactionInfo) ([]tagKey, []string) {
+	tagType := expenseCategoryType
+	if t.Direction == types.Credit {
+		tagType = incomeCategoryType
+	}
+
+	names := append(automaticTags(t), t.Tags...)
+	keys := make([]tagKey, len(names))
+	for i, name := range names {
+		keys[i] = tagKey{tagType, strings.ToLower(name)}
+	}
+
+	return keys, names
+}
+
+// ResolveTags adds the ids of the automatic tags and the tags of the rules to the transactions, creating the
+// tags that do not exist yet. The ids are resolved before any is added, so a tag that cannot be created is left
+// out of every transaction and the rest of the tags are still added
+func ResolveTags(toshlClient toshl.ApiClient, transactions []*types.TransactionInfo) error {
+	tags, err := toshlClient.GetTags()
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[tagKey]string)
+	for _, tag := range tags {
+		if !tag.Deleted {
+			ids[tagKey{tag.Type, strings.ToLower(tag.Name)}] = tag.ID
+		}
+	}
+
+	var errs []string
+	failed := make(map[tagKey]bool)
+	for _, t := range transactions {
+		keys, names := transactionTagKeys(t)
+		for i, key := range keys {
+			if _, ok := ids[key]; ok || failed[key] {
+				continue
+			}
+
+			tag := toshl.Tag{Name: names[i], Type: key.tagType}
+			if err := toshlClient.CreateTag(&tag); err != nil {
+				failed[key] = true
+				errs = append(errs, fmt.Sprintf("tag [%s]: %s", names[i], err))
+				continue
+			}
+			ids[key] = tag.ID
+		}
+	}
+
+	for _, t := range transactions {
+		keys, _ := transactionTagKeys(t)
+		for _, key := range keys {
+			id, ok := ids[key]
+			if ok && !containsString(t.TagIds, id) {
+				t.TagIds = append(t.TagIds, id)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("could not create tags: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package sync
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/bank/bancolombia"
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+func TestAutomaticTags(t *testing.T) {
+	purchase := &types.TransactionInfo{
+		Bank: bancolombia.Bancolombia{},
+		Type: "Compra",
+		Card: types.CreditCard,
+	}
+	if tags := automaticTags(purchase); !reflect.DeepEqual(tags, []string{"bancolombia", "crédito", "compra"}) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	// imported movements have no bank and transfers are not made with a card
+	transfer := &types.TransactionInfo{Type: "Transferencia"}
+	if tags := automaticTags(transfer); !reflect.DeepEqual(tags, []string{"transferencia"}) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+}
+
+func TestAddInstallmentsPlanTags(t *testing.T) {
+	newPurchase := func(installments int) *types.TransactionInfo {
+		return &types.TransactionInfo{Type: "Compra", Installments: installments, Tags: []string{"hogar"}}
+	}
+
+	inPlan, single := newPurchase(12), newPurchase(1)
+	AddInstallmentsPlanTags([]*types.TransactionInfo{inPlan, single}, types.InstallmentsModePlan)
+	if !reflect.DeepEqual(inPlan.Tags, []string{"hogar", "12 cuotas"}) {
+		t.Errorf("unexpected tags of the purchase in installments %v", inPlan.Tags)
+	}
+	if !reflect.DeepEqual(single.Tags, []string{"hogar"}) {
+		t.Errorf("unexpected tags of the single payment %v", single.Tags)
+	}
+
+	monthly := newPurchase(12)
+	AddInstallmentsPlanTags([]*types.TransactionInfo{monthly}, types.InstallmentsModeMonthly)
+	if !reflect.DeepEqual(monthly.Tags, []string{"hogar"}) {
+		t.Errorf("expected no plan tag when installments are booked monthly, got %v", monthly.Tags)
+	}
+}
+
+type fakeTagsClient struct {
+	toshl.ApiClient
+	tags    []toshl.Tag
+	failing string
+}
+
+func (c *fakeTagsClient) GetTags() ([]toshl.Tag, error) {
+	return c.tags, nil
+}
+
+func (c *fakeTagsClient) CreateTag(tag *toshl.Tag) error {
+	if tag.Name == c.failing {
+		return errors.New("tag limit reached")
+	}
+	tag.ID = "new-" + tag.Name
+	c.tags = append(c.tags, *tag)
+	return nil
+}
+
+func TestResolveTagsFailedTag(t *testing.T) {
+	client := &fakeTagsClient{
+		tags:    []toshl.Tag{{ID: "compra", Name: "Compra", Type: expenseCategoryType}},
+		failing: "hogar",
+	}
+
+	first := &types.TransactionInfo{Type: "Compra", Direction: types.Debit, Tags: []string{"hogar", "mercado"}}
+	second := &types.TransactionInfo{Type: "Compra", Direction: types.Debit, Tags: []string{"viajes"}}
+
+	if err := ResolveTags(client, []*types.TransactionInfo{first, second}); err == nil {
+		t.Errorf("expected an error for the tag that could not be created")
+	}
+
+	if !reflect.DeepEqual(first.TagIds, []string{"compra", "new-mercado"}) {
+		t.Errorf("unexpected tags of the first transaction %v", first.TagIds)
+	}
+	if !reflect.DeepEqual(second.TagIds, []string{"compra", "new-viajes"}) {
+		t.Errorf("unexpected tags of the second transaction %v", second.TagIds)
+	}
+}
@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/Philanthropists/toshl-email-autosync/internal/invoice"
 	"github.com/Philanthropists/toshl-email-autosync/internal/logger"
 	"github.com/Philanthropists/toshl-email-autosync/internal/sync/common"
 	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
 	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
-	_toshl "github.com/Philanthropists/toshl-go"
 )
 
 func GetMappableAccounts(accounts []*toshl.Account) map[string]*toshl.Account {
@@ -36,23 +37,85 @@ func GetMappableAccounts(accounts []*toshl.Account) map[string]*toshl.Account {
 	return mapping
 }
 
-func CreateInternalCategoryIfAbsent(toshlClient toshl.ApiClient) string {
+const (
+	expenseCategoryType = "expense"
+	incomeCategoryType  = "income"
+)
+
+// AddCashAccount maps the counterpart of cash withdrawals to the Toshl account with the given name
+func AddCashAccount(mappableAccounts map[string]*toshl.Account, accounts []*toshl.Account, name string) bool {
+	account, ok := findAccountByName(accounts, name)
+	if ok {
+		mappableAccounts[types.CashCounterpart] = account
+	}
+
+	return ok
+}
+
+func findAccountByName(accounts []*toshl.Account, name string) (*toshl.Account, bool) {
+	for _, account := range accounts {
+		if account.Name == name {
+			return account, true
+		}
+	}
+
+	return nil, false
+}
+
+// findMappableAccount looks for the account by its complete number first, and then by its last four
+// digits since that is how most accounts are named. It must only be used with numbers of our own accounts,
+// e.g. statements, since a third party account can end in the same digits
+func findMappableAccount(mappableAccounts map[string]*toshl.Account, number string) (*toshl.Account, bool) {
+	if account, ok := mappableAccounts[number]; ok {
+		return account, true
+	}
+
+	const lastDigits = 4
+	if len(number) > lastDigits {
+		account, ok := mappableAccounts[number[len(number)-lastDigits:]]
+		return account, ok
+	}
+
+	return nil, false
+}
+
+// resolveAccountNumbers replaces the complete account numbers of the transactions with the last four digits when
+// only those are mapped, statements and movements files write the complete number
+func resolveAccountNumbers(transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account) {
+	const lastDigits = 4
+
+	for _, t := range transactions {
+		if _, ok := mappableAccounts[t.Account]; ok || len(t.Account) <= lastDigits {
+			continue
+		}
+
+		if last := t.Account[len(t.Account)-lastDigits:]; mappableAccounts[last] != nil {
+			t.Account = last
+		}
+	}
+}
+
+func CreateInternalCategoryIfAbsent(toshlClient toshl.ApiClient, categoryType string) string {
 	const categoryName = "PENDING"
 
+	return CreateCategoryIfAbsent(toshlClient, categoryName, categoryType)
+}
+
+func CreateCategoryIfAbsent(toshlClient toshl.ApiClient, categoryName, categoryType string) string {
 	categories, err := toshlClient.GetCategories()
 	if err != nil {
 		panic(err)
 	}
 
 	for _, c := range categories {
-		if c.Name == categoryName {
+		if c.Name == categoryName && c.Type == categoryType {
 			return c.ID
 		}
 	}
 
 	var cat toshl.Category
 	cat.Name = categoryName
-	cat.Type = "expense"
+	cat.Type = categoryType
 
 	err = toshlClient.CreateCategory(&cat)
 	if err != nil {
@@ -62,40 +125,249 @@ func CreateInternalCategoryIfAbsent(toshlClient toshl.ApiClient) string {
 	return cat.ID
 }
 
-func CreateEntries(toshlClient toshl.ApiClient, transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account, internalCategoryId string) ([]*types.TransactionInfo, []*types.TransactionInfo) {
+func CreateInternalCategoriesIfAbsent(toshlClient toshl.ApiClient) map[types.Direction]string {
+	return map[types.Direction]string{
+		types.Debit:  CreateInternalCategoryIfAbsent(toshlClient, expenseCategoryType),
+		types.Credit: CreateInternalCategoryIfAbsent(toshlClient, incomeCategoryType),
+	}
+}
+
+// Categories are the Toshl categories of the entries created by the sync
+type Categories struct {
+	// Internal are the categories of entries that still have to be categorized by hand, by direction
+	Internal map[types.Direction]string
+	// Taxes are the categories of tax entries, by tax name
+	Taxes map[string]string
+	// Rules are the categories assigned by the categorization rules, by direction and name
+	Rules map[types.Direction]map[string]string
+}
+
+// entryCategory returns the category assigned to the transaction by the rules, or the internal one when
+// no rule matched it
+func entryCategory(t *types.TransactionInfo, categories Categories) string {
+	if id, ok := categories.Rules[t.Direction][t.Category]; ok && t.Category != "" {
+		return id
+	}
+	return categories.Internal[t.Direction]
+}
+
+// getOwnCounterpartAccount returns the account that received the money when it is one of our own accounts
+func getOwnCounterpartAccount(t *types.TransactionInfo, account *toshl.Account, mappableAccounts map[string]*toshl.Account) (*toshl.Account, bool) {
+	if t.Counterpart == "" || t.Direction != types.Debit {
+		return nil, false
+	}
+
+	// the counterpart can be anyone's account, so it is only ours when its complete number is mapped
+	counterpart, ok := mappableAccounts[t.Counterpart]
+	if !ok || counterpart.ID == account.ID {
+		return nil, false
+	}
+
+	return counterpart, true
+}
+
+func entryDescription(t *types.TransactionInfo) string {
 	const DateFormat = "2006-01-02"
 
+	description := fmt.Sprintf("** %s de %s", t.Type, placeName(t))
+	if t.Refund != nil && t.Refund.OriginalEntryId != "" {
+		description += fmt.Sprintf(" - reintegro de la compra del %s (%s)",
+			t.Refund.OriginalDate.Format(DateFormat), t.Refund.OriginalEntryId)
+	}
+	if t.Receipt != nil {
+		description += " - " + t.Receipt.Details
+	}
+	if t.Invoice != nil {
+		description += " - " + invoice.Summary(t.Invoice)
+	}
+
+	return description
+}
+
+// addMonths adds months to the date keeping its day, or using the last day of the month when it does not
+// have that day, e.g. January 31 plus one month is February 28
+func addMonths(date time.Time, months int) time.Time {
+	firstOfMonth := time.Date(date.Year(), date.Month()+time.Month(months), 1,
+		date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+
+	day := date.Day()
+	if lastDay := firstOfMonth.AddDate(0, 1, -1).Day(); day > lastDay {
+		day = lastDay
+	}
+
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
+
+// transactionEntries returns the entries that book the transaction, which are more than one only for
+// purchases in installments when they are booked monthly. Monthly installments are separate entries instead of
+// a Toshl repeating entry because the cents that cannot be divided make the first installment different, and
+// because each installment is checked for duplicates on its own, so a purchase that failed halfway is resumed
+func transactionEntries(t *types.TransactionInfo, account *toshl.Account, installmentsMode types.InstallmentsMode) []*toshl.Entry {
+	const DateFormat = "2006-01-02"
+
+	amount := t.Value
+	if t.Direction == types.Debit {
+		amount = amount.Neg() // negative because it is an expense
+	}
+
+	description := entryDescription(t)
+
+	newEntry := func(amount types.Money, date time.Time, description string) *toshl.Entry {
+		var entry toshl.Entry
+		entry.SetAmount(amount)
+		entry.Date = date.In(localLocation).Format(DateFormat)
+		entry.Description = &description
+		entry.Account = account.ID
+		return &entry
+	}
+
+	if t.Installments <= 1 {
+		return []*toshl.Entry{newEntry(amount, t.Date, description)}
+	}
+
+	switch installmentsMode {
+	case types.InstallmentsModeMonthly:
+		var entries []*toshl.Entry
+		for i, part := range amount.Split(t.Installments) {
+			installmentDescription := fmt.Sprintf("%s - cuota %d de %d", description, i+1, t.Installments)
+			entries = append(entries, newEntry(part, addMonths(t.Date, i), installmentDescription))
+		}
+		return entries
+	case types.InstallmentsModePlan:
+		description += fmt.Sprintf(" - a %d cuotas de %s", t.Installments, t.Value.Split(t.Installments)[0])
+	}
+
+	return []*toshl.Entry{newEntry(amount, t.Date, description)}
+}
+
+// taxEntries returns the expenses of the taxes charged because of the transaction
+func taxEntries(t *types.TransactionInfo, account *toshl.Account, taxCategoryIds map[string]string) []*toshl.Entry {
+	const DateFormat = "2006-01-02"
+
+	var entries []*toshl.Entry
+	for _, tax := range t.Taxes {
+		var entry toshl.Entry
+		entry.SetAmount(tax.Value.Neg())
+		entry.Date = t.Date.In(localLocation).Format(DateFormat)
+		description := fmt.Sprintf("** %s por %s de %s", tax.Name, t.Type, placeName(t))
+		entry.Description = &description
+		entry.Account = account.ID
+		entry.Category = taxCategoryIds[tax.Name]
+		entry.Tags = t.TagIds
+		entries = append(entries, &entry)
+	}
+
+	return entries
+}
+
+// CreateEntries books the transactions, skipping the ones that already have an entry, e.g. because their
+// messages were not archived in a previous run
+func CreateEntries(toshlClient toshl.ApiClient, ledger *Ledger, transactions []*types.TransactionInfo, mappableAccounts map[string]*toshl.Account, categories Categories, installmentsMode types.InstallmentsMode) ([]*types.TransactionInfo, []*types.TransactionInfo, []*types.TransactionInfo) {
 	log := logger.GetLogger()
 
+	existing, err := existingEntries(toshlClient, transactions, mappableAccounts)
+	if err != nil {
+		log.Errorw("could not get the existing entries, duplicates are not checked",
+			"error", err)
+	}
+	used := make(map[*toshl.Entry]bool)
+	entryOrigin := ledgerEntryOrigins(ledger)
+
 	var successfulTransactions []*types.TransactionInfo
 	var failedTransactions []*types.TransactionInfo
+	var skippedTransactions []*types.TransactionInfo
 	for _, t := range transactions {
 		account, ok := mappableAccounts[t.Account]
 		if !ok {
 			continue
 		}
 
-		var newEntry toshl.Entry
-		newEntry.Amount = -*t.Value.Rate // negative because it is an expense
-		newEntry.Currency = _toshl.Currency{
-			Code: "COP",
+		counterpart, isTransfer := getOwnCounterpartAccount(t, account, mappableAccounts)
+
+		mode := installmentsMode
+		if isTransfer {
+			mode = types.InstallmentsModeFull
+		}
+		// installments are checked one by one, so a transaction that failed halfway is resumed from the
+		// installments that were already created
+		var newEntries []*toshl.Entry
+		for _, newEntry := range transactionEntries(t, account, mode) {
+			if duplicate := findDuplicateEntry(existing, used, t, newEntry, entryOrigin); duplicate != nil {
+				used[duplicate] = true
+				t.EntryIds = append(t.EntryIds, *duplicate.Id)
+				continue
+			}
+			newEntries = append(newEntries, newEntry)
+		}
+
+		if len(newEntries) == 0 {
+			log.Infow("Skipped transaction that already has its entries",
+				"entries", t.EntryIds,
+				"place", t.Place)
+			skippedTransactions = append(skippedTransactions, t)
+			continue
+		}
+		if len(t.EntryIds) > 0 {
+			log.Infow("Resuming transaction that already has some of its entries",
+				"entries", t.EntryIds,
+				"place", t.Place)
+		}
+
+		var err error
+		if isTransfer {
+			newEntry := newEntries[0]
+			newEntry.Tags = t.TagIds
+			transfer := toshl.Transfer{
+				Account:  counterpart.ID,
+				Currency: newEntry.Currency,
+			}
+			err = toshlClient.CreateTransfer(newEntry, transfer)
+			if err == nil {
+				t.EntryIds = append(t.EntryIds, *newEntry.Id)
+				log.Infow("Created entry successfully",
+					"entry", newEntry)
+			}
+		} else {
+			for _, newEntry := range newEntries {
+				newEntry.Category = entryCategory(t, categories)
+				newEntry.Tags = t.TagIds
+				if err = toshlClient.CreateEntry(newEntry); err != nil {
+					// the installments that were already created are kept in the ledger with the failed
+					// transaction, the next run creates the rest
+					break
+				}
+				t.EntryIds = append(t.EntryIds, *newEntry.Id)
+
+				log.Infow("Created entry successfully",
+					"entry", newEntry)
+			}
+		}
+
+		// a tax that cannot be booked does not fail the transaction, whose entries were already created, it
+		// is reported on its own
+		if err == nil {
+			for i, taxEntry := range taxEntries(t, account, categories.Taxes) {
+				if taxErr := toshlClient.CreateEntry(taxEntry); taxErr != nil {
+					log.Errorw("Failed to create tax entry",
+						"tax", t.Taxes[i].Name,
+						"place", t.Place,
+						"error", taxErr)
+					t.FailedTaxes = append(t.FailedTaxes, t.Taxes[i])
+					continue
+				}
+
+				log.Infow("Created tax entry successfully",
+					"entry", taxEntry)
+			}
 		}
-		newEntry.Date = t.Date.In(localLocation).Format(DateFormat)
-		description := fmt.Sprintf("** %s de %s", t.Type, t.Place)
-		newEntry.Description = &description
-		newEntry.Account = account.ID
-		newEntry.Category = internalCategoryId
 
-		err := toshlClient.CreateEntry(&newEntry)
 		if err != nil {
-			log.Errorf("Failed to create entry for transaction [%+v | %+v]: %s\n", newEntry, t, err)
+			log.Errorf("Failed to create entry for transaction [%+v]: %s\n", t, err)
 			failedTransactions = append(failedTransactions, t)
 		} else {
-			log.Infow("Created entry successfully",
-				"entry", newEntry)
 			successfulTransactions = append(successfulTransactions, t)
 		}
 	}
 
-	return successfulTransactions, failedTransactions
+	return successfulTransactions, failedTransactions, skippedTransactions
 }
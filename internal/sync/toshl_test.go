@@ -0,0 +1,232 @@
+package sync
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
+	"github.com/Philanthropists/toshl-email-autosync/internal/toshl"
+)
+
+func TestTransactionEntriesInstallments(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+
+	value, _ := types.ParseMoney("COP", "100000")
+	purchase := &types.TransactionInfo{
+		Type:         "Compra",
+		Place:        "FALABELLA",
+		Value:        value,
+		Account:      "1234",
+		Date:         time.Date(2022, 1, 31, 15, 30, 0, 0, localLocation),
+		Installments: 3,
+	}
+
+	entries := transactionEntries(purchase, account, types.InstallmentsModeMonthly)
+
+	expected := []struct {
+		date        string
+		amount      float64
+		description string
+	}{
+		{"2022-01-31", -33333.34, "** Compra de FALABELLA - cuota 1 de 3"},
+		{"2022-02-28", -33333.33, "** Compra de FALABELLA - cuota 2 de 3"},
+		{"2022-03-31", -33333.33, "** Compra de FALABELLA - cuota 3 de 3"},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("got %d entries, expected %d", len(entries), len(expected))
+	}
+	for i, e := range expected {
+		entry := entries[i]
+		if entry.Date != e.date || entry.Amount != e.amount || *entry.Description != e.description {
+			t.Errorf("entry %d is [%s %v %s], expected [%s %v %s]", i,
+				entry.Date, entry.Amount, *entry.Description, e.date, e.amount, e.description)
+		}
+	}
+
+	entries = transactionEntries(purchase, account, types.InstallmentsModeFull)
+	if len(entries) != 1 || entries[0].Amount != -100000 {
+		t.Errorf("expected a single entry with the full amount, got %+v", entries)
+	}
+
+	entries = transactionEntries(purchase, account, types.InstallmentsModePlan)
+	if len(entries) != 1 || *entries[0].Description != "** Compra de FALABELLA - a 3 cuotas de COP 33,333.34" {
+		t.Errorf("expected a single entry with the plan, got %+v", entries)
+	}
+}
+
+func TestGetOwnCounterpartAccount(t *testing.T) {
+	newAccount := func(id string) *toshl.Account {
+		account := &toshl.Account{}
+		account.ID = id
+		return account
+	}
+	savings, card, savingsFull, cash := newAccount("savings"), newAccount("card"), newAccount("savings-full"), newAccount("cash")
+
+	mappableAccounts := map[string]*toshl.Account{
+		"5678":                savings,
+		"1234":                card,
+		"40212345678":         savingsFull,
+		types.CashCounterpart: cash,
+	}
+
+	cases := []struct {
+		name        string
+		counterpart string
+		direction   types.Direction
+		expected    *toshl.Account
+	}{
+		{"credit card payment", "1234", types.Debit, card},
+		{"transfer to a mapped complete number", "40212345678", types.Debit, savingsFull},
+		{"third party account ending in our digits", "99900001234", types.Debit, nil},
+		{"cash withdrawal", types.CashCounterpart, types.Debit, cash},
+		{"same account", "5678", types.Debit, nil},
+		{"incoming money", "1234", types.Credit, nil},
+		{"no counterpart", "", types.Debit, nil},
+	}
+
+	for _, c := range cases {
+		tx := &types.TransactionInfo{Counterpart: c.counterpart, Direction: c.direction}
+		account, ok := getOwnCounterpartAccount(tx, savings, mappableAccounts)
+		if c.expected == nil {
+			if ok {
+				t.Errorf("%s: unexpected transfer into [%s]", c.name, account.ID)
+			}
+			continue
+		}
+		if !ok || account != c.expected {
+			t.Errorf("%s: expected a transfer into [%s], got %+v", c.name, c.expected.ID, account)
+		}
+	}
+}
+
+// fakeToshlClient keeps the entries in memory, it fails every entry creation after the first failAfter ones
+type fakeToshlClient struct {
+	toshl.ApiClient
+	entries   []*toshl.Entry
+	updated   []*toshl.Entry
+	failAfter int
+}
+
+func (c *fakeToshlClient) CreateEntry(entry *toshl.Entry) error {
+	if c.failAfter == 0 {
+		return fmt.Errorf("service unavailable")
+	}
+	c.failAfter--
+
+	id := fmt.Sprintf("entry-%d", len(c.entries)+1)
+	entry.Id = &id
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+func (c *fakeToshlClient) UpdateEntry(entry *toshl.Entry) error {
+	c.updated = append(c.updated, entry)
+	return nil
+}
+
+func (c *fakeToshlClient) GetAccountsEntries(from, to time.Time, accounts []string) ([]*toshl.Entry, error) {
+	inAccounts := make(map[string]bool)
+	for _, account := range accounts {
+		inAccounts[account] = true
+	}
+
+	var entries []*toshl.Entry
+	for _, entry := range c.entries {
+		if inAccounts[entry.Account] && entry.Date >= from.Format("2006-01-02") && entry.Date <= to.Format("2006-01-02") {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func TestCreateEntriesResumesInstallments(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "card"
+	mappable := map[string]*toshl.Account{"1234": account}
+
+	value, _ := types.ParseMoney("COP", "90000")
+	newPurchase := func() *types.TransactionInfo {
+		return &types.TransactionInfo{
+			Type:         "Compra",
+			Place:        "FALABELLA",
+			Value:        value,
+			Account:      "1234",
+			Direction:    types.Debit,
+			Date:         time.Date(2022, 1, 31, 15, 30, 0, 0, localLocation),
+			Installments: 3,
+		}
+	}
+
+	client := &fakeToshlClient{failAfter: 1}
+	_, failed, _ := CreateEntries(client, nil, []*types.TransactionInfo{newPurchase()}, mappable, Categories{}, types.InstallmentsModeMonthly)
+	if len(failed) != 1 || len(client.entries) != 1 {
+		t.Fatalf("expected the transaction to fail after the first installment, got %d failed and %d entries", len(failed), len(client.entries))
+	}
+
+	client.failAfter = -1
+	successful, failed, skipped := CreateEntries(client, nil, []*types.TransactionInfo{newPurchase()}, mappable, Categories{}, types.InstallmentsModeMonthly)
+	if len(successful) != 1 || len(failed) != 0 || len(skipped) != 0 {
+		t.Fatalf("expected the transaction to be resumed, got %d successful, %d failed and %d skipped", len(successful), len(failed), len(skipped))
+	}
+	if len(client.entries) != 3 {
+		t.Fatalf("expected the 3 installments to be booked once, got %d entries", len(client.entries))
+	}
+	if ids := successful[0].EntryIds; len(ids) != 3 || ids[0] != "entry-1" {
+		t.Errorf("expected the entries of the 3 installments, got %v", ids)
+	}
+
+	_, _, skipped = CreateEntries(client, nil, []*types.TransactionInfo{newPurchase()}, mappable, Categories{}, types.InstallmentsModeMonthly)
+	if len(skipped) != 1 || len(client.entries) != 3 {
+		t.Errorf("expected the booked transaction to be skipped, got %d skipped and %d entries", len(skipped), len(client.entries))
+	}
+}
+
+func TestCreateEntriesFailedTax(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "savings"
+	mappable := map[string]*toshl.Account{"5678": account}
+
+	value, _ := types.ParseMoney("COP", "250000")
+	tax, _ := types.ParseMoney("COP", "1000")
+	payment := &types.TransactionInfo{
+		Type:      "Pago",
+		Place:     "CODENSA",
+		Value:     value,
+		Account:   "5678",
+		Direction: types.Debit,
+		Date:      time.Date(2022, 3, 14, 10, 0, 0, 0, localLocation),
+		Taxes:     []types.Tax{{Name: "GMF", Value: tax}},
+	}
+
+	client := &fakeToshlClient{failAfter: 1}
+	successful, failed, _ := CreateEntries(client, nil, []*types.TransactionInfo{payment}, mappable, Categories{}, types.InstallmentsModeFull)
+
+	if len(successful) != 1 || len(failed) != 0 {
+		t.Fatalf("expected the transaction to be booked, got %d successful and %d failed", len(successful), len(failed))
+	}
+	if len(payment.FailedTaxes) != 1 || payment.FailedTaxes[0].Name != "GMF" {
+		t.Errorf("expected the GMF to be reported as failed, got %+v", payment.FailedTaxes)
+	}
+}
+
+func TestTaxEntriesTags(t *testing.T) {
+	account := &toshl.Account{}
+	account.ID = "savings"
+
+	tax, _ := types.ParseMoney("COP", "1000")
+	payment := &types.TransactionInfo{
+		Type:   "Pago",
+		Place:  "CODENSA",
+		Date:   time.Date(2022, 3, 14, 10, 0, 0, 0, localLocation),
+		Taxes:  []types.Tax{{Name: "GMF", Value: tax}},
+		TagIds: []string{"bancolombia", "pago"},
+	}
+
+	entries := taxEntries(payment, account, map[string]string{"GMF": "taxes"})
+	if len(entries) != 1 || !reflect.DeepEqual(entries[0].Tags, payment.TagIds) {
+		t.Errorf("expected the tax entry to have the tags of the transaction, got %+v", entries)
+	}
+}
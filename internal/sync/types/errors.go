@@ -0,0 +1,87 @@
+package types
+
+import (
+	"fmt"
+)
+
+type ParseErrorReason int
+
+const (
+	ReasonUnexpected ParseErrorReason = iota
+	ReasonNoTemplateMatched
+	ReasonMissingField
+	ReasonBadAmount
+	ReasonUnknownAccount
+)
+
+func (r ParseErrorReason) String() string {
+	switch r {
+	case ReasonNoTemplateMatched:
+		return "no template matched"
+	case ReasonMissingField:
+		return "missing field"
+	case ReasonBadAmount:
+		return "bad amount"
+	case ReasonUnknownAccount:
+		return "unknown account"
+	default:
+		return "unexpected"
+	}
+}
+
+// ParseError describes why a bank message could not be turned into a transaction
+type ParseError struct {
+	Bank   string
+	Reason ParseErrorReason
+	// Field is the missing field for ReasonMissingField, or the account for ReasonUnknownAccount
+	Field  string
+	MsgId  uint32
+	Origin MessageOrigin
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%s: %s", e.Bank, e.Reason)
+	if e.Field != "" {
+		msg += fmt.Sprintf(" [%s]", e.Field)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func NewNoTemplateMatchedError(bank string) *ParseError {
+	return &ParseError{
+		Bank:   bank,
+		Reason: ReasonNoTemplateMatched,
+	}
+}
+
+func NewMissingFieldError(bank string, field string) *ParseError {
+	return &ParseError{
+		Bank:   bank,
+		Reason: ReasonMissingField,
+		Field:  field,
+	}
+}
+
+func NewBadAmountError(bank string, err error) *ParseError {
+	return &ParseError{
+		Bank:   bank,
+		Reason: ReasonBadAmount,
+		Err:    err,
+	}
+}
+
+func NewUnknownAccountError(bank string, account string) *ParseError {
+	return &ParseError{
+		Bank:   bank,
+		Reason: ReasonUnknownAccount,
+		Field:  account,
+	}
+}
@@ -0,0 +1,22 @@
+package types
+
+import "time"
+
+// Invoice is the electronic invoice of a purchase, as reported to the DIAN
+type Invoice struct {
+	Number    string
+	IssueDate time.Time
+	// SupplierNIT is the tax id of the merchant, without verification digit
+	SupplierNIT string
+	// SupplierName is the legal name of the merchant, which is usually different from the name in the alerts
+	SupplierName string
+	Lines        []InvoiceLine
+	Taxes        []Tax
+	Total        Money
+}
+
+type InvoiceLine struct {
+	Description string
+	Quantity    string
+	Amount      Money
+}
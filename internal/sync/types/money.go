@@ -0,0 +1,229 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/currency"
+)
+
+const DefaultCurrencyCode = "COP"
+
+// minorUnits are the ISO 4217 exponents that are different from the usual 2 decimal places
+var minorUnits = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"ISK": 0,
+	"IQD": 3,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"LYD": 3,
+	"OMR": 3,
+	"PYG": 0,
+	"TND": 3,
+	"UGX": 0,
+	"VND": 0,
+}
+
+// Money is an exact amount of a currency, expressed as an integer number of its minor units
+// (e.g. cents), so that no rounding happens while amounts move through the sync
+type Money struct {
+	Units    int64
+	Currency string
+}
+
+func MinorUnits(code string) int {
+	if units, ok := minorUnits[code]; ok {
+		return units
+	}
+	return 2
+}
+
+func pow10(n int) int64 {
+	result := int64(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+func validCurrencyCode(code string) (string, error) {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return "", fmt.Errorf("currency code [%s] is not valid: %w", code, err)
+	}
+	return unit.String(), nil
+}
+
+func NewMoneyFromUnits(code string, units int64) (Money, error) {
+	code, err := validCurrencyCode(code)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Units: units, Currency: code}, nil
+}
+
+// ParseMoney parses a plain decimal number like "-1234.5" (dot as decimal separator and no thousands
+// separator). It fails if the number has more decimals than the currency minor units, unless they are zeros
+func ParseMoney(code string, s string) (Money, error) {
+	return parseMoney(code, s, false)
+}
+
+// ParseMoneyRounded works like ParseMoney, but rounds half away from zero any decimal that does not fit in the
+// currency minor units. It should only be used for values that are not booked, like prices or rates
+func ParseMoneyRounded(code string, s string) (Money, error) {
+	return parseMoney(code, s, true)
+}
+
+func parseMoney(code string, s string, round bool) (Money, error) {
+	code, err := validCurrencyCode(code)
+	if err != nil {
+		return Money{}, err
+	}
+
+	scale := MinorUnits(code)
+
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	integer, decimal := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		integer, decimal = s[:i], s[i+1:]
+	}
+
+	if integer == "" && decimal == "" {
+		return Money{}, errors.New("amount cannot be empty")
+	}
+
+	for _, part := range []string{integer, decimal} {
+		if strings.Trim(part, "0123456789") != "" {
+			return Money{}, fmt.Errorf("amount [%s] is not a plain decimal number", s)
+		}
+	}
+
+	var roundUp bool
+	if len(decimal) > scale {
+		extra := decimal[scale:]
+		decimal = decimal[:scale]
+		if round {
+			roundUp = extra[0] >= '5'
+		} else if strings.Trim(extra, "0") != "" {
+			return Money{}, fmt.Errorf("amount [%s] has more decimals than the %d allowed for %s", s, scale, code)
+		}
+	}
+	decimal += strings.Repeat("0", scale-len(decimal))
+
+	digits := strings.TrimLeft(integer+decimal, "0")
+	var units int64
+	if digits != "" {
+		units, err = strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return Money{}, fmt.Errorf("amount [%s] is out of range: %w", s, err)
+		}
+	}
+
+	if roundUp {
+		units++
+	}
+
+	if negative {
+		units = -units
+	}
+
+	return Money{Units: units, Currency: code}, nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Units == 0
+}
+
+func (m Money) Neg() Money {
+	return Money{Units: -m.Units, Currency: m.Currency}
+}
+
+func (m Money) Abs() Money {
+	if m.Units < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Split divides the amount in n parts, the remainder that cannot be divided goes to the first part so
+// that the parts always add up to the amount
+func (m Money) Split(n int) []Money {
+	if n < 1 {
+		n = 1
+	}
+
+	part := m.Units / int64(n)
+	remainder := m.Units % int64(n)
+
+	parts := make([]Money, n)
+	for i := range parts {
+		parts[i] = Money{Units: part, Currency: m.Currency}
+	}
+	parts[0].Units += remainder
+
+	return parts
+}
+
+// Decimal returns the amount as a plain decimal number, e.g. "-1234.50"
+func (m Money) Decimal() string {
+	scale := MinorUnits(m.Currency)
+
+	sign := ""
+	units := m.Units
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+
+	factor := pow10(scale)
+	integer := strconv.FormatInt(units/factor, 10)
+	if scale == 0 {
+		return sign + integer
+	}
+
+	decimal := strconv.FormatInt(units%factor, 10)
+	decimal = strings.Repeat("0", scale-len(decimal)) + decimal
+
+	return sign + integer + "." + decimal
+}
+
+// Float64 returns the closest floating point number to the amount, it should only be used where an external
+// API requires it
+func (m Money) Float64() float64 {
+	value, _ := strconv.ParseFloat(m.Decimal(), 64)
+	return value
+}
+
+// String returns the amount with its currency and thousands separators, e.g. "COP 1,234.50"
+func (m Money) String() string {
+	decimal := m.Decimal()
+
+	sign := ""
+	if strings.HasPrefix(decimal, "-") {
+		sign = "-"
+		decimal = decimal[1:]
+	}
+
+	integer, fraction := decimal, ""
+	if i := strings.Index(decimal, "."); i >= 0 {
+		integer, fraction = decimal[:i], decimal[i:]
+	}
+
+	var grouped []string
+	for len(integer) > 3 {
+		grouped = append([]string{integer[len(integer)-3:]}, grouped...)
+		integer = integer[:len(integer)-3]
+	}
+	grouped = append([]string{integer}, grouped...)
+
+	return fmt.Sprintf("%s %s%s%s", m.Currency, sign, strings.Join(grouped, ","), fraction)
+}
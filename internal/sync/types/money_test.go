@@ -0,0 +1,96 @@
+package types
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		code     string
+		input    string
+		units    int64
+		decimal  string
+		str      string
+		hasError bool
+	}{
+		{code: "COP", input: "13900.00", units: 1390000, decimal: "13900.00", str: "COP 13,900.00"},
+		{code: "COP", input: "1234567.5", units: 123456750, decimal: "1234567.50", str: "COP 1,234,567.50"},
+		{code: "USD", input: "12.99", units: 1299, decimal: "12.99", str: "USD 12.99"},
+		{code: "USD", input: "-0.07", units: -7, decimal: "-0.07", str: "USD -0.07"},
+		{code: "JPY", input: "1500.0", units: 1500, decimal: "1500", str: "JPY 1,500"},
+		{code: "USD", input: "12.999", hasError: true},
+		{code: "USD", input: "12,99", hasError: true},
+		{code: "XXXX", input: "12.99", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code+" "+tt.input, func(t *testing.T) {
+			m, err := ParseMoney(tt.code, tt.input)
+			if tt.hasError {
+				if err == nil {
+					t.Fatalf("expected an error, got [%+v]", m)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if m.Units != tt.units {
+				t.Errorf("units: got [%d], expected [%d]", m.Units, tt.units)
+			}
+			if m.Decimal() != tt.decimal {
+				t.Errorf("decimal: got [%s], expected [%s]", m.Decimal(), tt.decimal)
+			}
+			if m.String() != tt.str {
+				t.Errorf("string: got [%s], expected [%s]", m.String(), tt.str)
+			}
+		})
+	}
+}
+
+func TestParseMoneyRounded(t *testing.T) {
+	m, err := ParseMoneyRounded("COP", "25123.456789")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if m.Decimal() != "25123.46" {
+		t.Errorf("got [%s], expected [25123.46]", m.Decimal())
+	}
+}
+
+func TestMoneySplit(t *testing.T) {
+	cases := []struct {
+		amount   string
+		n        int
+		expected []string
+	}{
+		{"1200000.00", 12, []string{"100000.00", "100000.00", "100000.00", "100000.00", "100000.00", "100000.00", "100000.00", "100000.00", "100000.00", "100000.00", "100000.00", "100000.00"}},
+		{"100.00", 3, []string{"33.34", "33.33", "33.33"}},
+		{"-100.00", 3, []string{"-33.34", "-33.33", "-33.33"}},
+		{"10.00", 0, []string{"10.00"}},
+	}
+
+	for _, c := range cases {
+		m, err := ParseMoney("COP", c.amount)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		parts := m.Split(c.n)
+		if len(parts) != len(c.expected) {
+			t.Fatalf("%s / %d: got %d parts, expected %d", c.amount, c.n, len(parts), len(c.expected))
+		}
+
+		var total int64
+		for i, part := range parts {
+			total += part.Units
+			if part.Decimal() != c.expected[i] {
+				t.Errorf("%s / %d: part %d is [%s], expected [%s]", c.amount, c.n, i, part.Decimal(), c.expected[i])
+			}
+		}
+
+		if total != m.Units {
+			t.Errorf("%s / %d: parts add up to %d, expected %d", c.amount, c.n, total, m.Units)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package types
+
+import (
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
+)
+
+// Receipt is the order sent by a merchant by email, it is matched to the transaction that paid it
+type Receipt struct {
+	Merchant string
+	Total    Money
+	Date     time.Time
+	// Details describes what was bought, e.g. the restaurant and the items of a delivery
+	Details string
+}
+
+// Enricher reads the receipts of a merchant, so that the transactions paid to it get a useful description
+type Enricher interface {
+	Name() string
+	FilterMessage(message types.Message) bool
+	ExtractReceiptFromMessage(message types.Message) (*Receipt, error)
+	// MatchesPlace tells if a transaction with the place written in the bank alert was paid to the merchant
+	MatchesPlace(place string) bool
+}
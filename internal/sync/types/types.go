@@ -1,10 +1,11 @@
 package types
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Philanthropists/toshl-email-autosync/internal/datasource/imap/types"
-	"github.com/Philanthropists/toshl-go"
 )
 
 type Auth struct {
@@ -18,10 +19,136 @@ type Auth struct {
 	TwilioToNumber   string `json:"twilio-to-number"`
 	RapidApiKey      string `json:"rapidapi-key"`
 	RapidApiHost     string `json:"rapidapi-host"`
+
+	BankDefinitionsDir string `json:"bank-definitions-dir"`
+	CashAccount        string `json:"cash-account"`
+	// InstallmentsMode is how purchases paid in installments are booked, see InstallmentsMode
+	InstallmentsMode InstallmentsMode `json:"installments-mode"`
+	// GMF enables the 4x1000 tax entries for the configured accounts
+	GMF GMFConfig `json:"gmf"`
+	// Statements configures the reminders of credit card statements
+	Statements StatementsConfig `json:"statements"`
+	// PDFStatements configures the reconciliation of the statements sent as PDF attachments
+	PDFStatements PDFStatementsConfig `json:"pdf-statements"`
+	// EnrichWithInvoices adds the merchant and items of the electronic invoices found in the mailbox to the entries
+	EnrichWithInvoices bool `json:"enrich-with-invoices"`
+	// EnrichWithReceipts adds the order details of the receipts sent by merchants like Rappi to the entries
+	EnrichWithReceipts bool `json:"enrich-with-receipts"`
+	// MerchantAliasesFile is a .json or .yaml file with merchant aliases that take precedence over the built-in ones
+	MerchantAliasesFile string `json:"merchant-aliases-file"`
+	// CategoryRulesFile is a .json or .yaml file with the ordered rules that categorize the entries
+	CategoryRulesFile string `json:"category-rules-file"`
+	// Classifier predicts the category and tags of the entries that no rule matched, learning from the history
+	Classifier ClassifierConfig `json:"classifier"`
+}
+
+type ClassifierConfig struct {
+	Enabled bool `json:"enabled"`
+	// Threshold is the minimum probability of a prediction for it to be applied, 0.8 by default
+	Threshold float64 `json:"threshold"`
+	// HistoryMonths are the months of entries the classifier learns from, 24 by default
+	HistoryMonths uint `json:"history-months"`
+	// RetrainDays is the age after which the persisted model is trained again, 7 by default
+	RetrainDays uint `json:"retrain-days"`
+}
+
+type PDFStatementsConfig struct {
+	// Senders are the addresses that send the statements, statements are not read when it is empty
+	Senders []string `json:"senders"`
+	// Password opens encrypted statements, it is usually the national ID
+	Password string `json:"password"`
+	// CardAccounts are the last digits of the credit cards, whose statements list purchases as positive amounts
+	// and payments as negative ones
+	CardAccounts []string `json:"card-accounts"`
+}
+
+// IsCardAccount tells if the statements of the account are of a credit card
+func (c PDFStatementsConfig) IsCardAccount(account string) bool {
+	for _, card := range c.CardAccounts {
+		if card != "" && strings.HasSuffix(account, card) {
+			return true
+		}
+	}
+	return false
+}
+
+type StatementsConfig struct {
+	// PaymentAccount is the name of the Toshl account the cards are paid from, when it is empty statements are
+	// only notified
+	PaymentAccount string `json:"payment-account"`
+	// ReminderDays is how many days before the due date Toshl reminds the payment
+	ReminderDays uint `json:"reminder-days"`
+}
+
+type GMFConfig struct {
+	Accounts []GMFAccount `json:"accounts"`
+	// ExemptThreshold is the amount in pesos that can be debited every month from exempt accounts without
+	// paying the tax, e.g. "14844000"
+	ExemptThreshold string `json:"exempt-threshold"`
+}
+
+type GMFAccount struct {
+	// Number is the account number or its last four digits, as written in the alerts
+	Number string `json:"number"`
+	// Exempt is set for the account marked as exempt in the bank, only one account per person can be
+	Exempt bool `json:"exempt"`
+}
+
+// InstallmentsMode is how a credit card purchase paid in several monthly installments (cuotas) is booked
+type InstallmentsMode string
+
+const (
+	// InstallmentsModeFull books the full amount on the purchase date, it is the default
+	InstallmentsModeFull InstallmentsMode = ""
+	// InstallmentsModeMonthly books one entry per installment, one month apart, starting on the purchase date
+	InstallmentsModeMonthly InstallmentsMode = "monthly"
+	// InstallmentsModePlan books the full amount on the purchase date and notes the plan in the entry
+	InstallmentsModePlan InstallmentsMode = "plan"
+)
+
+func (m InstallmentsMode) Valid() bool {
+	switch m {
+	case InstallmentsModeFull, InstallmentsModeMonthly, InstallmentsModePlan:
+		return true
+	default:
+		return false
+	}
+}
+
+// MessageOrigin identifies the message that something was read from, unlike the sequence number it does not
+// change when messages are moved
+type MessageOrigin struct {
+	// MessageId is the RFC 5322 Message-ID
+	MessageId   string
+	UIDValidity uint32
+	UID         uint32
+}
+
+func NewMessageOrigin(msg types.Message) MessageOrigin {
+	var origin MessageOrigin
+	if msg.Message == nil {
+		return origin
+	}
+
+	origin.UIDValidity = msg.UIDValidity
+	origin.UID = msg.Uid
+	if msg.Envelope != nil {
+		origin.MessageId = msg.Envelope.MessageId
+	}
+
+	return origin
 }
 
-type Currency struct {
-	toshl.Currency
+// Key is the Message-ID, or the UIDVALIDITY and UID of the message when it does not have one. It is empty when
+// the origin is unknown, e.g. for movements files
+func (o MessageOrigin) Key() string {
+	if o.MessageId != "" {
+		return o.MessageId
+	}
+	if o.UID != 0 {
+		return fmt.Sprintf("%d/%d", o.UIDValidity, o.UID)
+	}
+	return ""
 }
 
 type BankMessage struct {
@@ -30,17 +157,119 @@ type BankMessage struct {
 	Bank BankDelegate
 }
 
+type Direction int
+
+const (
+	// Debit is money going out of the account, it is the zero value since most alerts are expenses
+	Debit Direction = iota
+	// Credit is money coming into the account
+	Credit
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Debit:
+		return "debit"
+	case Credit:
+		return "credit"
+	default:
+		return "unknown"
+	}
+}
+
+type DateSource string
+
+const (
+	DateSourceEnvelope DateSource = "envelope"
+	DateSourceBody     DateSource = "body"
+	// DateSourceImport is the date of the row of an imported movements file
+	DateSourceImport DateSource = "import"
+)
+
+// CardType is the kind of card used in a transaction
+type CardType string
+
+const (
+	CreditCard CardType = "credit"
+	DebitCard  CardType = "debit"
+)
+
 type TransactionInfo struct {
-	Bank    BankDelegate
-	MsgId   uint32
-	Type    string
-	Place   string
-	Value   Currency
-	Account string
-	Date    time.Time
+	Bank  BankDelegate
+	MsgId uint32
+	// Origin identifies the message of the transaction in the ledger
+	Origin MessageOrigin
+	Type   string
+	Place  string
+	// Merchant is the canonical name of the merchant of Place, empty when no merchant alias matches it
+	Merchant string
+	Value    Money
+	Account  string
+	// Card is the kind of card of the account, empty when it is not a card or the bank does not tell
+	Card CardType
+	Date time.Time
+	// DateSource tells if Date was written in the message body or taken from the envelope
+	DateSource DateSource
+	Direction  Direction
+	// Counterpart is the account number that received the money, when the bank states it
+	Counterpart string
+	// Refund is set when the transaction gives back the money of a previous purchase
+	Refund *Refund
+	// Installments is the number of monthly payments of a credit card purchase, zero means a single payment
+	Installments int
+	// Taxes are charged because of the transaction without a message of their own, e.g. the GMF
+	Taxes []Tax
+	// FailedTaxes could not be booked after the transaction was, they are reported to be booked by hand
+	FailedTaxes []Tax
+	// Invoice is the electronic invoice of the purchase, when it was received
+	Invoice *Invoice
+	// Receipt is the order details sent by the merchant, when it was received
+	Receipt *Receipt
+	// Category is the name of the Toshl category assigned by the categorization rules, the entries of
+	// transactions without one go to PENDING
+	Category string
+	// Tags are the names of the Toshl tags assigned by the categorization rules and of the installments plan
+	Tags []string
+	// TagIds are Toshl tags attached to the entries, e.g. the ones predicted from the history
+	TagIds []string
+	// EntryIds are the Toshl entries that book the transaction, or the entry it duplicates when it was skipped
+	EntryIds []string
+}
+
+type Tax struct {
+	Name  string
+	Value Money
+}
+
+// CashCounterpart is the counterpart of cash withdrawals, it maps to the account configured as cash account
+const CashCounterpart = "cash"
+
+type Refund struct {
+	// OriginalEntryId is the Toshl entry of the refunded purchase, it is empty when it was not found
+	OriginalEntryId string
+	OriginalDate    time.Time
 }
 
 type BankDelegate interface {
+	Name() string
 	FilterMessage(message types.Message) bool
 	ExtractTransactionInfoFromMessage(message types.Message) (*TransactionInfo, error)
 }
+
+// StatementInfo is the summary of a credit card statement, it is not a transaction
+type StatementInfo struct {
+	Bank           BankDelegate
+	MsgId          uint32
+	Origin         MessageOrigin
+	Account        string
+	Balance        Money
+	MinimumPayment Money
+	DueDate        time.Time
+}
+
+// StatementDelegate is implemented by the banks that also send credit card statements, their messages must not
+// be kept by FilterMessage
+type StatementDelegate interface {
+	FilterStatementMessage(message types.Message) bool
+	ExtractStatementInfoFromMessage(message types.Message) (*StatementInfo, error)
+}
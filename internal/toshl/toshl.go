@@ -1,6 +1,17 @@
 package toshl
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Philanthropists/toshl-email-autosync/internal/sync/types"
 	_toshl "github.com/Philanthropists/toshl-go"
 )
 
@@ -12,15 +23,57 @@ type Entry struct {
 	_toshl.Entry
 }
 
+// SetAmount sets the entry amount and currency, this is the only place where amounts stop being exact since
+// Toshl represents them as floating point numbers
+func (e *Entry) SetAmount(amount types.Money) {
+	e.Amount = amount.Float64()
+	e.Currency = _toshl.Currency{
+		Code: amount.Currency,
+	}
+}
+
+// Money returns the entry amount as an exact amount, rounded to the minor units of its currency
+func (e Entry) Money() (types.Money, error) {
+	return types.ParseMoneyRounded(e.Currency.Code, strconv.FormatFloat(e.Amount, 'f', -1, 64))
+}
+
 type Category struct {
 	_toshl.Category
 }
 
+// Tag is a Toshl tag, toshl-go does not support tags so they are requested through its HTTP client
+type Tag struct {
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// Transfer is the counterpart of an entry that moves money between two accounts
+type Transfer struct {
+	Account  string          `json:"account"`
+	Currency _toshl.Currency `json:"currency"`
+}
+
+// Reminder makes Toshl remind a planned entry some periods before its date, e.g. 3 days before
+type Reminder struct {
+	Period string `json:"period"`
+	Number uint   `json:"number"`
+}
+
 type ApiClient interface {
 	GetAccounts() ([]*Account, error)
 	CreateEntry(entry *Entry) error
+	UpdateEntry(entry *Entry) error
+	CreateTransfer(entry *Entry, transfer Transfer) error
+	CreatePlannedTransfer(entry *Entry, transfer Transfer, reminders []Reminder) error
+	GetEntries(from, to time.Time) ([]*Entry, error)
+	GetEntriesHistory(from, to time.Time) ([]*Entry, error)
+	GetAccountsEntries(from, to time.Time, accounts []string) ([]*Entry, error)
 	GetCategories() ([]Category, error)
 	CreateCategory(category *Category) error
+	GetTags() ([]Tag, error)
+	CreateTag(tag *Tag) error
 }
 
 func NewApiClient(token string) ApiClient {
@@ -32,6 +85,8 @@ type toshlClient interface {
 	Accounts(params *_toshl.AccountQueryParams) ([]_toshl.Account, error)
 	CreateCategory(category *_toshl.Category) error
 	CreateEntry(entry *_toshl.Entry) error
+	Entries(params *_toshl.EntryQueryParams) ([]_toshl.Entry, error)
+	GetHTTPClient() _toshl.HTTPClient
 }
 
 type clientImpl struct {
@@ -60,6 +115,39 @@ func (c clientImpl) CreateCategory(category *Category) error {
 	return nil
 }
 
+func (c clientImpl) GetTags() ([]Tag, error) {
+	responses, err := c.client.GetHTTPClient().GetMultiple("tags", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []Tag
+	for _, response := range responses {
+		var responseTags []Tag
+		if err := json.Unmarshal([]byte(response), &responseTags); err != nil {
+			return nil, err
+		}
+		tags = append(tags, responseTags...)
+	}
+
+	return tags, nil
+}
+
+func (c clientImpl) CreateTag(tag *Tag) error {
+	jsonBytes, err := json.Marshal(tag)
+	if err != nil {
+		return err
+	}
+
+	id, err := c.client.GetHTTPClient().Post("tags", string(jsonBytes))
+	if err != nil {
+		return err
+	}
+
+	tag.ID = id
+	return nil
+}
+
 func (c clientImpl) CreateEntry(entry *Entry) error {
 	if err := c.client.CreateEntry(&entry.Entry); err != nil {
 		return err
@@ -67,6 +155,189 @@ func (c clientImpl) CreateEntry(entry *Entry) error {
 	return nil
 }
 
+// UpdateEntry replaces the entry with the same id, toshl-go cannot update entries so the entry is sent directly
+// through its HTTP client. The response must be the updated entry, otherwise the update failed
+func (c clientImpl) UpdateEntry(entry *Entry) error {
+	if entry.Id == nil {
+		return errors.New("cannot update an entry without id")
+	}
+
+	jsonBytes, err := json.Marshal(entry.Entry)
+	if err != nil {
+		return err
+	}
+
+	apiUrl := "entries/" + *entry.Id
+
+	var response string
+	if rest, ok := c.client.GetHTTPClient().(*_toshl.RestHTTPClient); ok {
+		response, err = put(rest, apiUrl, string(jsonBytes))
+	} else {
+		response, err = c.client.GetHTTPClient().Update(apiUrl, string(jsonBytes))
+	}
+	if err != nil {
+		return err
+	}
+
+	var updated _toshl.Entry
+	if err := json.Unmarshal([]byte(response), &updated); err != nil {
+		return fmt.Errorf("entry [%s] was not updated: %w", *entry.Id, err)
+	}
+	if updated.Id == nil || *updated.Id != *entry.Id {
+		return fmt.Errorf("entry [%s] was not updated: %s", *entry.Id, response)
+	}
+
+	entry.Entry = updated
+	return nil
+}
+
+// put sends a PUT request with the credentials of the toshl-go HTTP client, whose Update does not check the
+// status of the response
+func put(rest *_toshl.RestHTTPClient, apiUrl, payload string) (string, error) {
+	req, err := http.NewRequest(http.MethodPut, rest.BaseURL+"/"+apiUrl, strings.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+rest.Token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", _toshl.GetUserAgentString())
+
+	resp, err := rest.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("PUT %s failed with status %d: %s", apiUrl, resp.StatusCode, body)
+	}
+
+	return string(body), nil
+}
+
+// CreateTransfer creates an entry that moves money from the entry account into the transfer account,
+// toshl-go does not support transfers so the entry is posted directly through its HTTP client
+func (c clientImpl) CreateTransfer(entry *Entry, transfer Transfer) error {
+	return c.postTransfer(entry, transfer, nil)
+}
+
+// CreatePlannedTransfer creates a transfer dated in the future that Toshl reminds before it is due
+func (c clientImpl) CreatePlannedTransfer(entry *Entry, transfer Transfer, reminders []Reminder) error {
+	return c.postTransfer(entry, transfer, reminders)
+}
+
+func (c clientImpl) postTransfer(entry *Entry, transfer Transfer, reminders []Reminder) error {
+	payload := struct {
+		Amount      float64         `json:"amount"`
+		Currency    _toshl.Currency `json:"currency"`
+		Date        string          `json:"date"`
+		Description *string         `json:"desc,omitempty"`
+		Account     string          `json:"account"`
+		Tags        []string        `json:"tags,omitempty"`
+		Transaction Transfer        `json:"transaction"`
+		Reminders   []Reminder      `json:"reminders,omitempty"`
+	}{
+		Amount:      entry.Amount,
+		Currency:    entry.Currency,
+		Date:        entry.Date,
+		Description: entry.Description,
+		Account:     entry.Account,
+		Tags:        entry.Tags,
+		Transaction: transfer,
+		Reminders:   reminders,
+	}
+
+	jsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	id, err := c.client.GetHTTPClient().Post("entries", string(jsonBytes))
+	if err != nil {
+		return err
+	}
+
+	entry.Id = &id
+	return nil
+}
+
+func (c clientImpl) GetEntries(from, to time.Time) ([]*Entry, error) {
+	params := &_toshl.EntryQueryParams{
+		From: _toshl.Date(from),
+		To:   _toshl.Date(to),
+	}
+
+	entries, err := c.client.Entries(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var nEntries []*Entry
+	for _, entry := range entries {
+		nEntry := &Entry{entry}
+		nEntries = append(nEntries, nEntry)
+	}
+
+	return nEntries, nil
+}
+
+// GetAccountsEntries returns the entries of the given accounts between both dates, toshl-go cannot filter
+// by account so the entries are requested through its HTTP client
+func (c clientImpl) GetAccountsEntries(from, to time.Time, accounts []string) ([]*Entry, error) {
+	const dateFormat = "2006-01-02"
+
+	query := url.Values{}
+	query.Set("from", from.Format(dateFormat))
+	query.Set("to", to.Format(dateFormat))
+	query.Set("accounts", strings.Join(accounts, ","))
+
+	responses, err := c.client.GetHTTPClient().GetMultiple("entries", query.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, response := range responses {
+		var responseEntries []_toshl.Entry
+		if err := json.Unmarshal([]byte(response), &responseEntries); err != nil {
+			return nil, err
+		}
+
+		for _, entry := range responseEntries {
+			entries = append(entries, &Entry{entry})
+		}
+	}
+
+	return entries, nil
+}
+
+// GetEntriesHistory returns the entries of a long period, asking for a year at a time so that every request
+// stays small
+func (c clientImpl) GetEntriesHistory(from, to time.Time) ([]*Entry, error) {
+	var entries []*Entry
+	for start := from; !start.After(to); {
+		end := start.AddDate(1, 0, -1)
+		if end.After(to) {
+			end = to
+		}
+
+		yearEntries, err := c.GetEntries(start, end)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, yearEntries...)
+
+		start = end.AddDate(0, 0, 1)
+	}
+
+	return entries, nil
+}
+
 func (c clientImpl) GetAccounts() ([]*Account, error) {
 	accounts, err := c.client.Accounts(nil)
 	if err != nil {
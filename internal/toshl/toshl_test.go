@@ -0,0 +1,54 @@
+package toshl
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_toshl "github.com/Philanthropists/toshl-go"
+)
+
+func newTestClient(handler http.HandlerFunc) (clientImpl, func()) {
+	server := httptest.NewServer(handler)
+	rest := &_toshl.RestHTTPClient{BaseURL: server.URL, Token: "token", Client: server.Client()}
+	return clientImpl{client: _toshl.NewClient("token", rest)}, server.Close
+}
+
+func TestUpdateEntry(t *testing.T) {
+	var tests = []struct {
+		name    string
+		status  int
+		body    string
+		success bool
+	}{
+		{name: "updated", status: http.StatusOK, body: `{"id":"42","amount":-45000,"date":"2022-03-14","desc":"updated"}`, success: true},
+		{name: "conflict", status: http.StatusConflict, body: `{"error_id":"error.object.conflict"}`, success: false},
+		{name: "other entry", status: http.StatusOK, body: `{"id":"43"}`, success: false},
+		{name: "not an entry", status: http.StatusOK, body: `{"error_id":"error.unknown"}`, success: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPut || r.URL.Path != "/entries/42" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				_, _ = io.ReadAll(r.Body)
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.body))
+			})
+			defer closeServer()
+
+			id, description := "42", "updated"
+			entry := &Entry{}
+			entry.Id = &id
+			entry.Description = &description
+
+			err := client.UpdateEntry(entry)
+			if (err == nil) != test.success {
+				t.Errorf("expected success to be %v, got error %v", test.success, err)
+			}
+		})
+	}
+}